@@ -0,0 +1,159 @@
+package dropbox
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RequestIDHeader is the response header carrying the id Dropbox assigned to a
+// request, which its support needs to investigate a call.
+const RequestIDHeader = "X-Dropbox-Request-Id"
+
+// An AuditRecord describes a call which may have changed a dropbox.
+type AuditRecord struct {
+	Time      time.Time       `json:"time"`
+	Account   string          `json:"account,omitempty"` // Account of the Session, or its app key
+	Member    string          `json:"member,omitempty"`  // Team member acted as, if any
+	Method    string          `json:"method"`
+	Endpoint  string          `json:"endpoint"`         // URL path, including the file path of version 1 file calls
+	Params    url.Values      `json:"params,omitempty"` // Query and form parameters, without signatures
+	Arg       json.RawMessage `json:"arg,omitempty"`    // JSON argument of version 2 calls
+	Status    int             `json:"status,omitempty"` // Zero if no response was received
+	RequestID string          `json:"request_id,omitempty"`
+	Duration  time.Duration   `json:"duration"`
+	Error     string          `json:"error,omitempty"`
+}
+
+// An AuditLog records every call which may change a dropbox, that is all but
+// GET requests, except restores, and known read-only calls, as JSON lines, one
+// AuditRecord per line. Records are written once the response headers are
+// received, so a call which failed to be sent is recorded too. It is safe for
+// concurrent use.
+type AuditLog struct {
+	mu     sync.Mutex
+	w      io.Writer
+	closer io.Closer
+	err    error
+}
+
+// NewAuditLog creates an AuditLog writing to w.
+func NewAuditLog(w io.Writer) *AuditLog {
+	return &AuditLog{w: w}
+}
+
+// OpenAuditLog creates an AuditLog appending to the file filename, which is
+// created if needed, with permissions allowing only the current user access.
+func OpenAuditLog(filename string) (*AuditLog, error) {
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &AuditLog{w: f, closer: f}, nil
+}
+
+// Err returns the first error writing a record, if any. Records which failed
+// to be written are lost, but calls are not stopped.
+func (a *AuditLog) Err() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.err
+}
+
+// Close closes the file of an AuditLog created by OpenAuditLog.
+func (a *AuditLog) Close() error {
+	if a.closer == nil {
+		return nil
+	}
+	return a.closer.Close()
+}
+
+// Write writes r as a line of the log.
+func (a *AuditLog) Write(r *AuditRecord) error {
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, err := a.w.Write(append(line, '\n')); err != nil {
+		if a.err == nil {
+			a.err = err
+		}
+		return err
+	}
+	return nil
+}
+
+// audit records req in the AuditLog of the Session, unless it only reads data.
+func (c *Client) audit(req *http.Request, resp *http.Response, err error, d time.Duration) {
+	if c.Audit == nil || readOnly(req) {
+		return
+	}
+	r := &AuditRecord{
+		Time:     time.Now().Add(-d),
+		Account:  c.account(),
+		Member:   c.member,
+		Method:   req.Method,
+		Endpoint: req.URL.Path,
+		Params:   requestParams(req),
+		Duration: d,
+	}
+	if arg := req.Header.Get(APIArgHeader); arg != "" {
+		r.Arg = json.RawMessage(arg)
+	} else if req.Header.Get("Content-Type") == "application/json" {
+		if body := requestBody(req); json.Valid(body) {
+			r.Arg = body
+		}
+	}
+	if resp != nil {
+		r.Status = resp.StatusCode
+		r.RequestID = resp.Header.Get(RequestIDHeader)
+	}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	c.Audit.Write(r)
+}
+
+// requestParams returns the query and form parameters of req, leaving out
+// OAuth signatures.
+func requestParams(req *http.Request) url.Values {
+	params := req.URL.Query()
+	if req.Header.Get("Content-Type") == "application/x-www-form-urlencoded" {
+		if form, err := url.ParseQuery(string(requestBody(req))); err == nil {
+			for k, v := range form {
+				params[k] = append(params[k], v...)
+			}
+		}
+	}
+	for k := range params {
+		if strings.HasPrefix(k, "oauth_") {
+			delete(params, k)
+		}
+	}
+	if len(params) == 0 {
+		return nil
+	}
+	return params
+}
+
+// requestBody returns a copy of the body of req, if it can be read again.
+func requestBody(req *http.Request) []byte {
+	if req.GetBody == nil {
+		return nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil
+	}
+	defer body.Close()
+	data, _ := ioutil.ReadAll(io.LimitReader(body, MaxErrorBody))
+	return data
+}
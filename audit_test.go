@@ -0,0 +1,44 @@
+package dropbox
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAuditLogRecordsWrites(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"path": "/a.txt", "rev": "2"}`))
+	}))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	s := NewSession("key", "secret", nil, nil)
+	s.OAuth2Token = "token"
+	s.SetBaseURLs(BaseURLs{API: srv.URL, Content: srv.URL})
+	s.Audit = NewAuditLog(&buf)
+	c := NewClient(s, DropboxRoot)
+
+	if _, _, err := c.Metadata("/a.txt", 0, "", false, false, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Restore("/a.txt", "1"); err != nil {
+		t.Fatal(err)
+	}
+
+	var endpoints []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var r AuditRecord
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("%q: %v", line, err)
+		}
+		endpoints = append(endpoints, r.Method+" "+r.Endpoint)
+	}
+	if want := "GET /1/restore/dropbox/a.txt"; len(endpoints) != 1 || endpoints[0] != want {
+		t.Errorf("audited %q, want only %q", endpoints, want)
+	}
+}
@@ -0,0 +1,344 @@
+// Package backup copies a local directory into a dropbox, or a folder of a
+// dropbox into a local directory.
+//
+// Backups are incremental: a file is only uploaded when it differs from the
+// copy in the dropbox, judged by its size and modification time, and if a
+// state file is used, by its content hash. With a state file and TrustState,
+// unchanged files are recognized without any requests to Dropbox at all.
+// Files deleted locally can be pruned from the dropbox. Every run produces a
+// Report, which can be written out as JSON for monitoring.
+package backup
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cookieo9/dropbox-go"
+)
+
+// Options control a backup.
+type Options struct {
+	// Prune deletes files and folders from the dropbox which no longer
+	// exist locally.
+	Prune bool
+
+	// StateFile, if set, records the size, modification time and hash of
+	// every file uploaded, so files whose modification time changed
+	// without their content changing aren't uploaded again.
+	StateFile string
+
+	// TrustState, if StateFile is set, skips listing the dropbox and
+	// relies on the state file alone, so a run where nothing changed
+	// makes no requests. Files are only rehashed if their size or
+	// modification time changed. Changes made to the backup in the
+	// dropbox by others go unnoticed, and Prune only deletes files
+	// recorded in the state file, leaving behind their empty folders.
+	TrustState bool
+
+	// DryRun reports what would be done without changing the dropbox.
+	DryRun bool
+}
+
+// A FileError is a file which couldn't be backed up or restored.
+type FileError struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+// A Report describes the outcome of a run.
+type Report struct {
+	Local    string    `json:"local"`
+	Remote   string    `json:"remote"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	DryRun   bool      `json:"dry_run,omitempty"`
+
+	Uploaded    []string    `json:"uploaded,omitempty"`
+	Downloaded  []string    `json:"downloaded,omitempty"`
+	Deleted     []string    `json:"deleted,omitempty"`
+	Unchanged   int         `json:"unchanged"`
+	Bytes       int64       `json:"bytes"`
+	Failed      []FileError `json:"failed"`
+	Unverified  []string    `json:"unverified,omitempty"`
+	Interrupted string      `json:"interrupted,omitempty"`
+}
+
+// OK reports whether the run completed without any failures.
+func (r *Report) OK() bool {
+	return r.Interrupted == "" && len(r.Failed) == 0 && len(r.Unverified) == 0
+}
+
+// WriteFile writes the report to filename as JSON.
+func (r *Report) WriteFile(filename string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, append(data, '\n'), 0644)
+}
+
+func (r *Report) fail(p string, err error) {
+	r.Failed = append(r.Failed, FileError{p, err.Error()})
+}
+
+// fileState is what the state file remembers about a file, which is keyed by
+// its normalized relative path. State files written before content hashes were
+// used have a SHA-256 hash instead, which is no longer compared.
+type fileState struct {
+	Size        int64     `json:"size"`
+	ModTime     time.Time `json:"mtime"`
+	ContentHash string    `json:"content_hash"`
+	Rev         string    `json:"rev"`
+}
+
+func loadState(filename string) (map[string]fileState, error) {
+	state := make(map[string]fileState)
+	if filename == "" {
+		return state, nil
+	}
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return state, json.Unmarshal(data, &state)
+}
+
+func saveState(filename string, state map[string]fileState) error {
+	if filename == "" {
+		return nil
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	tmp := filename + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
+
+// listRemote returns the entries below the folder remote, keyed by their
+// normalized path relative to it. A missing folder has no entries.
+func listRemote(c *dropbox.Client, remote string) (map[string]*dropbox.Metadata, error) {
+	entries := make(map[string]*dropbox.Metadata)
+	root := dropbox.NormalizePath(remote)
+	err := c.Walk(remote, func(meta *dropbox.Metadata) error {
+		entries[relPath(root, dropbox.NormalizePath(meta.Path))] = meta
+		return nil
+	})
+	if errors.Is(err, dropbox.ErrNotFound) {
+		return entries, nil
+	}
+	return entries, err
+}
+
+// relPath returns p relative to root, both normalized remote paths.
+func relPath(root, p string) string {
+	if root == "/" {
+		return strings.TrimPrefix(p, "/")
+	}
+	return strings.TrimPrefix(strings.TrimPrefix(p, root), "/")
+}
+
+// sameTime compares modification times at the one second precision kept by
+// Dropbox.
+func sameTime(a, b time.Time) bool {
+	return a.Unix() == b.Unix()
+}
+
+// Backup uploads the files in the local directory to the remote folder, if
+// they differ from the remote copies. opts may be nil. An error is only
+// returned if the run couldn't proceed at all, failures of individual files
+// are recorded in the report.
+func Backup(c *dropbox.Client, local, remote string, opts *Options) (*Report, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	report := &Report{Local: local, Remote: remote, Started: time.Now(), DryRun: opts.DryRun}
+	defer func() { report.Finished = time.Now() }()
+
+	state, err := loadState(opts.StateFile)
+	if err != nil {
+		return nil, err
+	}
+	offline := opts.TrustState && opts.StateFile != ""
+	var remoteEntries map[string]*dropbox.Metadata
+	if offline {
+		remoteEntries = stateEntries(remote, state)
+	} else if remoteEntries, err = listRemote(c, remote); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	// Paths which couldn't be read, whose remote copies mustn't be pruned.
+	unreadable := make(map[string]bool)
+	err = filepath.Walk(local, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			report.fail(name, err)
+			if rel, rerr := filepath.Rel(local, name); rerr == nil {
+				unreadable[strings.ToLower(filepath.ToSlash(rel))] = true
+			}
+			if info != nil && info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(local, name)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		key := strings.ToLower(rel)
+		seen[key] = true
+		if info.IsDir() || !info.Mode().IsRegular() {
+			return nil
+		}
+
+		same := false
+		if offline {
+			same = unchangedOffline(name, info, state, key)
+		} else {
+			same = unchanged(name, info, remoteEntries[key], state, key)
+		}
+		if same {
+			report.Unchanged++
+			return nil
+		}
+		if opts.DryRun {
+			report.Uploaded = append(report.Uploaded, rel)
+			report.Bytes += info.Size()
+			return nil
+		}
+
+		hash, err := dropbox.ContentHashFile(name)
+		if err != nil {
+			report.fail(rel, err)
+			return nil
+		}
+		uploaded, err := c.UploadFromPath(name, path.Join(remote, rel), &dropbox.UploadOptions{Overwrite: true})
+		if err != nil {
+			report.fail(rel, err)
+			return nil
+		}
+		state[key] = fileState{info.Size(), info.ModTime(), hash, uploaded.Rev}
+		report.Uploaded = append(report.Uploaded, rel)
+		report.Bytes += info.Size()
+		return nil
+	})
+	if err != nil {
+		report.Interrupted = err.Error()
+	}
+
+	if opts.Prune && err == nil && !unreadable["."] {
+		prune(c, remote, remoteEntries, seen, unreadable, state, opts.DryRun, report)
+	}
+	if !opts.DryRun {
+		if err := saveState(opts.StateFile, state); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// unchanged reports whether the local file name is the same as the remote
+// file meta.
+func unchanged(name string, info os.FileInfo, meta *dropbox.Metadata, state map[string]fileState, key string) bool {
+	if meta == nil || meta.IsDir || meta.Bytes != info.Size() {
+		return false
+	}
+	s, ok := state[key]
+	if !ok || s.Rev != meta.Rev {
+		// Without a record of the upload, trust the times.
+		return !ok && sameTime(meta.ClientMTime.Time, info.ModTime())
+	}
+	if s.Size == info.Size() && sameTime(s.ModTime, info.ModTime()) {
+		return true
+	}
+	hash, err := dropbox.ContentHashFile(name)
+	if err != nil || hash != s.ContentHash {
+		return false
+	}
+	s.ModTime = info.ModTime()
+	state[key] = s
+	return true
+}
+
+// unchangedOffline reports whether the local file name is the same as when it
+// was last uploaded, according to the state alone.
+func unchangedOffline(name string, info os.FileInfo, state map[string]fileState, key string) bool {
+	s, ok := state[key]
+	if !ok || s.Size != info.Size() {
+		return false
+	}
+	if sameTime(s.ModTime, info.ModTime()) {
+		return true
+	}
+	hash, err := dropbox.ContentHashFile(name)
+	if err != nil || hash != s.ContentHash {
+		return false
+	}
+	s.ModTime = info.ModTime()
+	state[key] = s
+	return true
+}
+
+// stateEntries returns stand-ins for the remote entries of the files recorded
+// in the state, for pruning without listing the dropbox.
+func stateEntries(remote string, state map[string]fileState) map[string]*dropbox.Metadata {
+	entries := make(map[string]*dropbox.Metadata, len(state))
+	for key, s := range state {
+		entries[key] = &dropbox.Metadata{Path: path.Join(remote, key), Bytes: s.Size, Rev: s.Rev}
+	}
+	return entries
+}
+
+// prune deletes the remote entries which weren't seen locally. Entries inside
+// a deleted folder are not deleted separately. Entries at or below a local
+// path which couldn't be read are kept, as they may still exist.
+func prune(c *dropbox.Client, remote string, entries map[string]*dropbox.Metadata, seen, unreadable map[string]bool, state map[string]fileState, dryRun bool, report *Report) {
+	var gone []string
+	for key := range entries {
+		if !seen[key] && !unreadable[key] && !insideAny(key, unreadable) {
+			gone = append(gone, key)
+		}
+	}
+	sort.Strings(gone)
+
+	deleted := make(map[string]bool)
+	for _, key := range gone {
+		if insideAny(key, deleted) {
+			delete(state, key)
+			continue
+		}
+		if !dryRun {
+			if _, err := c.Delete(entries[key].Path); err != nil {
+				report.fail(entries[key].Path, err)
+				continue
+			}
+		}
+		delete(state, key)
+		deleted[key] = true
+		report.Deleted = append(report.Deleted, relPath(dropbox.NormalizePath(remote), dropbox.NormalizePath(entries[key].Path)))
+	}
+}
+
+// insideAny reports whether key is inside one of the folders.
+func insideAny(key string, folders map[string]bool) bool {
+	for dir := path.Dir(key); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		if folders[dir] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,180 @@
+package backup
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/cookieo9/dropbox-go"
+)
+
+// RestoreOptions control a restore.
+type RestoreOptions struct {
+	// AsOf, if not zero, restores the files as they were at that time,
+	// using their revisions. Files deleted since then are restored too.
+	AsOf time.Time
+
+	// Include and Exclude are path.Match patterns, matched against both
+	// the path of an entry relative to the remote folder and its name. If
+	// Include is not empty, only files matching one of its patterns are
+	// restored. Files and folders matching a pattern of Exclude are
+	// skipped.
+	Include []string
+	Exclude []string
+
+	// Verify checks every restored file against its remote metadata once
+	// all files have been downloaded.
+	Verify bool
+
+	// DryRun reports what would be done without writing any files.
+	DryRun bool
+}
+
+func matchAny(patterns []string, rel string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, rel); ok {
+			return true
+		}
+		if ok, _ := path.Match(p, path.Base(rel)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Restore downloads the files in the remote folder into the local directory,
+// replacing existing local files. opts may be nil. An error is only returned if
+// the run couldn't proceed at all, failures of individual files are recorded
+// in the report.
+func Restore(c *dropbox.Client, remote, local string, opts *RestoreOptions) (*Report, error) {
+	if opts == nil {
+		opts = &RestoreOptions{}
+	}
+	report := &Report{Local: local, Remote: remote, Started: time.Now(), DryRun: opts.DryRun}
+	defer func() { report.Finished = time.Now() }()
+
+	root, err := c.ContentMetadata(remote)
+	if err != nil {
+		return nil, err
+	}
+	if !root.IsDir {
+		return nil, errors.New(remote + " is not a folder")
+	}
+
+	restored := make(map[string]*dropbox.Metadata)
+	if err := restoreDir(c, dropbox.NormalizePath(remote), remote, local, opts, report, restored); err != nil {
+		report.Interrupted = err.Error()
+		return report, nil
+	}
+
+	if opts.Verify && !opts.DryRun {
+		for rel, meta := range restored {
+			info, err := os.Stat(filepath.Join(local, filepath.FromSlash(rel)))
+			if err != nil || info.Size() != meta.Bytes {
+				report.Unverified = append(report.Unverified, rel)
+			}
+		}
+	}
+	return report, nil
+}
+
+func restoreDir(c *dropbox.Client, root, dir, local string, opts *RestoreOptions, report *Report, restored map[string]*dropbox.Metadata) error {
+	meta, _, err := c.Metadata(dir, dropbox.MaxFileLimit, "", true, !opts.AsOf.IsZero(), "")
+	if err != nil {
+		return err
+	}
+	for i := range meta.Contents {
+		entry := &meta.Contents[i]
+		rel := relPath(root, dropbox.NormalizePath(entry.Path))
+		if len(rel) <= len(entry.Path) {
+			// Keep the case of the remote names.
+			rel = entry.Path[len(entry.Path)-len(rel):]
+		}
+		if matchAny(opts.Exclude, rel) {
+			continue
+		}
+		if entry.IsDir {
+			if err := restoreDir(c, root, entry.Path, local, opts, report, restored); err != nil {
+				return err
+			}
+			continue
+		}
+		if len(opts.Include) > 0 && !matchAny(opts.Include, rel) {
+			continue
+		}
+
+		rev := ""
+		if !opts.AsOf.IsZero() {
+			revMeta, err := c.RevisionAsOf(entry.Path, opts.AsOf)
+			if err != nil {
+				report.fail(rel, err)
+				continue
+			}
+			if revMeta == nil {
+				continue // didn't exist at the time
+			}
+			entry, rev = revMeta, revMeta.Rev
+		} else if entry.IsDeleted {
+			continue
+		}
+
+		report.Downloaded = append(report.Downloaded, rel)
+		report.Bytes += entry.Bytes
+		if opts.DryRun {
+			continue
+		}
+		target := filepath.Join(local, filepath.FromSlash(rel))
+		if err := download(c, entry, rev, target); err != nil {
+			report.fail(rel, err)
+			continue
+		}
+		restored[rel] = entry
+	}
+	return nil
+}
+
+// download writes the file meta, at revision rev if not empty, to target,
+// giving it the file's modification time.
+func download(c *dropbox.Client, meta *dropbox.Metadata, rev, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	body, _, err := c.GetFile(meta.Path, rev)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	f, err := ioutil.TempFile(filepath.Dir(target), "."+filepath.Base(target)+".tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	_, err = io.Copy(f, body)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp, 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp, target)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	mtime := meta.ClientMTime.Time
+	if mtime.IsZero() {
+		mtime = meta.Modified.Time
+	}
+	if !mtime.IsZero() {
+		return os.Chtimes(target, mtime, mtime)
+	}
+	return nil
+}
@@ -1,11 +1,16 @@
 package dropbox
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"io"
-	"io/ioutil"
+	"mime/multipart"
 	"net/http"
+	"net/url"
+	"path"
 	"strconv"
+	"time"
 )
 
 // The AccessRoot type represents the enumeration of Dropbox Root options either "sandbox", where the
@@ -23,7 +28,19 @@ const (
 // A Client provides access to the Dropbox services.
 type Client struct {
 	*Session
-	root AccessRoot
+	root     AccessRoot
+	member   string    // team member to act as, if any
+	pathRoot *PathRoot // namespace to resolve paths in, if any
+	retry    *RetryPolicy
+	reauth   ReauthorizeFunc
+	header   http.Header // extra headers sent with every request
+	params   url.Values  // extra parameters sent with every request
+	ctx      context.Context
+	deadline time.Time // transfers must complete by then, if not zero
+
+	// observe, if set, is told the outcome and duration of every attempt
+	// of a request.
+	observe func(resp *http.Response, err error, d time.Duration)
 }
 
 // URLs for all the Dropbox REST-API Calls
@@ -33,6 +50,7 @@ const (
 	FilesPutURL            = ContentPrefix + "/files_put"
 	MetadataURL            = APIPrefix + "/metadata"
 	DeltaURL               = APIPrefix + "/delta"
+	LongpollDeltaURL       = NotifyPrefix + "/longpoll_delta"
 	RevisionsURL           = APIPrefix + "/revisions"
 	RestoreURL             = APIPrefix + "/restore"
 	SearchURL              = APIPrefix + "/search"
@@ -62,12 +80,126 @@ func NewClient(session *Session, root AccessRoot) *Client {
 	}
 }
 
+// WithRoot returns a copy of the client which works on the given Dropbox Root.
+// The copy is cheap to make and shares the client's Session, so apps with access
+// to both roots don't need to maintain separate clients.
+func (c *Client) WithRoot(root AccessRoot) *Client {
+	clone := *c
+	clone.root = root
+	return &clone
+}
+
+// AsMember returns a copy of the client which acts on behalf of the team member
+// with the given id, by sending the TeamMemberHeader with every request. The
+// client must be authorized with a team token. Since the copy is cheap, it can
+// be used for a single call, eg: c.AsMember(id).Metadata(...). An empty id
+// returns a client acting as the token's own user.
+func (c *Client) AsMember(memberID string) *Client {
+	clone := *c
+	clone.member = memberID
+	return &clone
+}
+
+// WithPathRoot returns a copy of the client which resolves paths against the
+// given namespace, by sending the PathRootHeader with every request.
+func (c *Client) WithPathRoot(pr PathRoot) *Client {
+	clone := *c
+	clone.pathRoot = &pr
+	return &clone
+}
+
+// WithHeader returns a copy of the client which sends the given header with
+// every request, in addition to any added before, eg: for conditional requests
+// or tracing, c.WithHeader("X-Request-Id", id).Metadata(...). Headers set by
+// the package itself, such as Authorization, take precedence.
+func (c *Client) WithHeader(key, value string) *Client {
+	clone := *c
+	clone.header = c.header.Clone()
+	if clone.header == nil {
+		clone.header = make(http.Header)
+	}
+	clone.header.Add(key, value)
+	return &clone
+}
+
+// WithParams returns a copy of the client which sends the given parameters
+// with every request, in addition to any added before. This allows parameters
+// the package doesn't support yet to be used, eg:
+// c.WithParams(url.Values{"include_media_info": {"true"}}).Metadata(...).
+// Parameters set by the call itself take precedence. They are not sent with
+// version 2 calls, which take all their arguments as JSON.
+func (c *Client) WithParams(params url.Values) *Client {
+	clone := *c
+	clone.params = make(url.Values, len(c.params)+len(params))
+	for k, v := range c.params {
+		clone.params[k] = append([]string(nil), v...)
+	}
+	for k, v := range params {
+		clone.params[k] = append(clone.params[k], v...)
+	}
+	return &clone
+}
+
+// WithContext returns a copy of the client whose requests are sent with ctx,
+// so they are abandoned when ctx is canceled or its deadline passes, and fail
+// with its error.
+func (c *Client) WithContext(ctx context.Context) *Client {
+	clone := *c
+	clone.ctx = ctx
+	return &clone
+}
+
+// Member returns the id of the team member the client acts on behalf of, if any.
+func (c *Client) Member() string {
+	return c.member
+}
+
+// Root returns the Dropbox Root the client works on.
+func (c *Client) Root() AccessRoot {
+	return c.root
+}
+
 // AccountInfo performs the account/info API call and returns the result.
 func (c *Client) AccountInfo() (account *AccountInfo, err error) {
 	err = c.getJSON(AccountInfoURL, c.makeParams(true), &account)
 	return
 }
 
+// DefaultAccountInfoTTL is the default time CachedAccountInfo reuses a response.
+const DefaultAccountInfoTTL = 5 * time.Minute
+
+// CachedAccountInfo returns the result of the account/info API call, reusing a
+// previous result if it is younger than the Session's AccountInfoTTL. The cache
+// is shared by all Clients using the same Session. The returned value is shared
+// and must not be modified.
+func (c *Client) CachedAccountInfo() (*AccountInfo, error) {
+	ttl := c.AccountInfoTTL
+	if ttl <= 0 {
+		ttl = DefaultAccountInfoTTL
+	}
+
+	c.accountMu.Lock()
+	defer c.accountMu.Unlock()
+	if c.accountInfo != nil && time.Since(c.accountFetched) < ttl {
+		return c.accountInfo, nil
+	}
+
+	account, err := c.AccountInfo()
+	if err != nil {
+		return nil, err
+	}
+	c.accountInfo, c.accountFetched = account, time.Now()
+	return account, nil
+}
+
+// InvalidateAccountInfo discards the result cached by CachedAccountInfo, so
+// the next call fetches it again.
+func (c *Client) InvalidateAccountInfo() {
+	c.accountMu.Lock()
+	defer c.accountMu.Unlock()
+	c.accountInfo = nil
+}
+
 // GetFile downloads the data for a single file as a io.ReadCloser, as well as fetches
 // its metadata.
 func (c *Client) GetFile(path string, rev string) (io.ReadCloser, *Metadata, error) {
@@ -76,7 +208,49 @@ func (c *Client) GetFile(path string, rev string) (io.ReadCloser, *Metadata, err
 	if rev != "" {
 		params.Set("rev", rev)
 	}
-	return c.fileAccess(uri, params)
+	if c.Events == nil {
+		return c.fileAccess(uri, params)
+	}
+
+	c.emit(OperationEvent{Kind: DownloadStarted, Path: path})
+	body, meta, err := c.fileAccess(uri, params)
+	if err != nil {
+		c.emit(OperationEvent{Kind: DownloadFailed, Path: path, Err: err})
+		return nil, nil, err
+	}
+	return &observedBody{ReadCloser: body, c: c, path: path, meta: meta}, meta, nil
+}
+
+// GetFileIfChanged downloads the file at path unless its current revision is
+// knownRev, in which case unmodified is true and no data is transferred. The
+// download is pinned to the revision found in the metadata, so the returned
+// data always matches the returned metadata.
+func (c *Client) GetFileIfChanged(path, knownRev string) (data io.ReadCloser, meta *Metadata, unmodified bool, err error) {
+	meta, _, err = c.Metadata(path, 0, "", false, false, "")
+	if err != nil {
+		return nil, nil, false, err
+	}
+	if knownRev != "" && meta.Rev == knownRev {
+		return nil, meta, true, nil
+	}
+
+	data, meta, err = c.GetFile(path, meta.Rev)
+	return data, meta, false, err
+}
+
+// ContentMetadata returns the metadata for the file at path as reported by the
+// content server. The file request is made but the connection is closed as soon
+// as the headers are read, so little or none of the file's data is transferred.
+func (c *Client) ContentMetadata(path string) (*Metadata, error) {
+	body, meta, err := c.fileAccess(FilesURL+c.filePath(path), c.makeParams(false))
+	if err != nil {
+		return nil, err
+	}
+	body.Close()
+	if meta == nil {
+		return nil, errors.New("no metadata in response")
+	}
+	return meta, nil
 }
 
 // Thumbnail downloads a thumbnail image for the given path. If either format or size
@@ -93,10 +267,62 @@ func (c *Client) Thumbnail(path, format, size string) (io.ReadCloser, *Metadata,
 	return c.fileAccess(uri, params)
 }
 
+// UploadOptions control how an uploaded file is committed to the dropbox. All
+// the options are sent, so the zero value neither overwrites nor renames,
+// unlike passing nil options, which leaves the server defaults.
+type UploadOptions struct {
+	// Overwrite replaces any existing file at the path.
+	Overwrite bool
+
+	// ParentRev is the revision of the file being replaced, if any. If the
+	// file has since changed, the upload conflicts with it.
+	ParentRev string
+
+	// Autorename saves a conflicting upload under a new name, eg:
+	// "name (1).ext", rather than failing.
+	Autorename bool
+
+	// ClientMTime, if not zero, is sent as the modification time of the
+	// local copy of the file, and is reported as ClientMTime in its Metadata.
+	ClientMTime time.Time
+
+	// serverDefaults leaves Overwrite and Autorename to the server, as
+	// nil options do.
+	serverDefaults bool
+}
+
+// withMTime returns a copy of o with ClientMTime set to mtime unless it has
+// one. A nil o results in options which still leave the server defaults.
+func (o *UploadOptions) withMTime(mtime time.Time) *UploadOptions {
+	clone := UploadOptions{serverDefaults: true}
+	if o != nil {
+		clone = *o
+	}
+	if clone.ClientMTime.IsZero() {
+		clone.ClientMTime = mtime
+	}
+	return &clone
+}
+
+func (o *UploadOptions) setParams(params url.Values) {
+	if o == nil {
+		return
+	}
+	if !o.serverDefaults {
+		params.Set("overwrite", strconv.FormatBool(o.Overwrite))
+		params.Set("autorename", strconv.FormatBool(o.Autorename))
+	}
+	if o.ParentRev != "" {
+		params.Set("parent_rev", o.ParentRev)
+	}
+	if !o.ClientMTime.IsZero() {
+		params.Set("client_mtime", o.ClientMTime.UTC().Format(time.RFC1123Z))
+	}
+}
+
 // PutFile uploads size bytes from the given io.Reader as the contents of a file at the
 // given url. If parentRev is not the empty string, it is set as part of the request.
 func (c Client) PutFile(path string, overwrite bool, parentRev string, data io.Reader, size int64) (meta *Metadata, err error) {
-	uri := FilesPutURL + c.filePath(path)
 	params := c.makeParams(true)
 	if overwrite {
 		params.Set("overwrite", "true")
@@ -104,7 +330,68 @@ func (c Client) PutFile(path string, overwrite bool, parentRev string, data io.R
 	if parentRev != "" {
 		params.Set("parent_rev", parentRev)
 	}
+	return c.putFile(path, params, data, size)
+}
+
+// PutFileWithOptions uploads size bytes from the given io.Reader as the contents
+// of a file at the given path, as controlled by opts. A nil opts leaves the
+// server defaults, which overwrite existing files and rename conflicting
+// uploads, while a zero UploadOptions does neither. The file may be renamed on
+// conflict, so the path it was saved at is the Path of the returned Metadata.
+func (c *Client) PutFileWithOptions(path string, data io.Reader, size int64, opts *UploadOptions) (meta *Metadata, err error) {
+	params := c.makeParams(true)
+	opts.setParams(params)
+	return c.putFile(path, params, data, size)
+}
+
+func (c *Client) putFile(path string, params url.Values, data io.Reader, size int64) (meta *Metadata, err error) {
+	done := c.trackUpload(path)
+	uri := FilesPutURL + c.filePath(path)
 	err = c.putJSON(uri, params, &meta, data, size)
+	done(meta, size, err)
+	return
+}
+
+// PostFile uploads the data from the given io.Reader as a file named filename in
+// the folder at path, using a multipart form POST rather than a PUT. The length
+// of the data need not be known. If parentRev is not the empty string, it is
+// set as part of the request.
+func (c *Client) PostFile(path, filename string, overwrite bool, parentRev string, data io.Reader) (meta *Metadata, err error) {
+	uri := FilesURL + c.filePath(path)
+	params := c.makeParams(true)
+	if overwrite {
+		params.Set("overwrite", "true")
+	}
+	if parentRev != "" {
+		params.Set("parent_rev", parentRev)
+	}
+
+	pr, pw := io.Pipe()
+	form := multipart.NewWriter(pw)
+	go func() {
+		part, err := form.CreateFormFile("file", filename)
+		if err == nil {
+			_, err = copyBuffered(part, data)
+		}
+		if err == nil {
+			err = form.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+	defer pr.Close()
+
+	req, err := c.newRequest("POST", uri, params, pr, 0)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	r, err := c.doRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	defer drainAndClose(r.Body)
+	err = c.decodeJSON(r, &meta)
 	return
 }
 
@@ -164,14 +451,42 @@ func (c *Client) Search(path, query string, fileLimit int, deleted bool) (meta [
 // the empty string, then changes from the creation of the dropbox are
 // given, otherwise, changes since the mentioned cursor are given.
 func (c *Client) Delta(cursor string) (delta *Delta, err error) {
+	return c.DeltaPrefix(cursor, "")
+}
+
+// DeltaPrefix is like Delta, but if pathPrefix is not the empty string only
+// changes to files and folders at or below that path are given. A cursor must
+// always be used with the same prefix.
+func (c *Client) DeltaPrefix(cursor, pathPrefix string) (delta *Delta, err error) {
 	params := c.makeParams(true)
 	if cursor != "" {
 		params.Set("cursor", cursor)
 	}
+	if pathPrefix != "" {
+		params.Set("path_prefix", path.Clean("/"+pathPrefix))
+	}
 	err = c.postFormJSON(DeltaURL, params, &delta)
 	return
 }
 
+// LongpollDelta blocks until there are changes after the given delta cursor,
+// or timeout seconds (between 30 and 480, the server default if 0) have
+// passed, and reports whether there are changes. If backoff is not zero, the
+// client must wait that many seconds before calling LongpollDelta again.
+func (c *Client) LongpollDelta(cursor string, timeout int) (changes bool, backoff int, err error) {
+	params := c.makeParams(false)
+	params.Set("cursor", cursor)
+	if timeout > 0 {
+		params.Set("timeout", strconv.Itoa(timeout))
+	}
+	var res struct {
+		Changes bool `json:"changes"`
+		Backoff int  `json:"backoff"`
+	}
+	err = c.getJSON(LongpollDeltaURL, params, &res)
+	return res.Changes, res.Backoff, err
+}
+
 // Media gets a URL to the given path that is accessible without login.
 // It is expected to not last long.
 func (c *Client) Media(path string) (media *Share, err error) {
@@ -238,20 +553,17 @@ func (c *Client) ChunkedUpload(uploadId string, offset int64, data io.Reader, si
 
 	// When offset does not match, 400 status and expected state are returned.
 	if r.StatusCode == http.StatusBadRequest {
-		apierr := &APIError{
-			Code: r.StatusCode,
-		}
-		if body, err := ioutil.ReadAll(r.Body); err != nil {
-			return nil, err
-		} else if err := json.Unmarshal(body, &state); err != nil {
+		apierr, err := newAPIError(r)
+		if err != nil {
 			return nil, err
-		} else if err := json.Unmarshal(body, apierr); err != nil {
+		}
+		if err := json.Unmarshal(apierr.Body, &state); err != nil {
 			return nil, err
 		}
 		return &state, apierr
 	}
 
-	if err := parseJSON(r, &state); err != nil {
+	if err := c.decodeJSON(r, &state); err != nil {
 		return nil, err
 	}
 	return &state, nil
@@ -259,12 +571,16 @@ func (c *Client) ChunkedUpload(uploadId string, offset int64, data io.Reader, si
 
 // CommitChunkedUpload commits a chunked upload.
 func (c *Client) CommitChunkedUpload(path string, overwrite bool, parentRev, uploadId string) (meta *Metadata, err error) {
+	return c.CommitChunkedUploadWithOptions(path, uploadId, &UploadOptions{Overwrite: overwrite, ParentRev: parentRev, Autorename: true})
+}
+
+// CommitChunkedUploadWithOptions commits a chunked upload as controlled by opts,
+// which may be nil, as in PutFileWithOptions. The file may be renamed on conflict, so the path it was
+// saved at is the Path of the returned Metadata.
+func (c *Client) CommitChunkedUploadWithOptions(path, uploadId string, opts *UploadOptions) (meta *Metadata, err error) {
 	uri := CommitChunkedUploadURL + c.filePath(path)
 	params := c.makeParams(true)
-	params.Set("overwrite", strconv.FormatBool(overwrite))
-	if parentRev != "" {
-		params.Set("parent_rev", parentRev)
-	}
+	opts.setParams(params)
 	params.Set("upload_id", uploadId)
 	err = c.postFormJSON(uri, params, &meta)
 	return
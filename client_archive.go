@@ -0,0 +1,101 @@
+package dropbox
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+// archivePath returns the name of p in an archive of the folder root.
+func archivePath(root, p string) string {
+	root, p = path.Clean("/"+root), path.Clean("/"+p)
+	if len(p) <= len(root) {
+		return ""
+	}
+	return strings.TrimPrefix(p[len(root):], "/")
+}
+
+// modTime returns the best modification time of a file to record in an
+// archive.
+func (m *Metadata) modTime() time.Time {
+	if !m.ClientMTime.IsZero() {
+		return m.ClientMTime.Time
+	}
+	return m.Modified.Time
+}
+
+// ZipFolder writes a zip archive of the folder at path and everything inside
+// it to w, with names relative to the folder. Files are streamed from Dropbox
+// into the archive one at a time, so they are never held in memory.
+func (c *Client) ZipFolder(path string, w io.Writer) error {
+	zw := zip.NewWriter(w)
+	err := c.Walk(path, func(meta *Metadata) error {
+		header := &zip.FileHeader{
+			Name:     archivePath(path, meta.Path),
+			Modified: meta.modTime(),
+		}
+		if meta.IsDir {
+			header.Name += "/"
+			_, err := zw.CreateHeader(header)
+			return err
+		}
+		header.Method = zip.Deflate
+		fw, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		_, _, err = c.Download(meta.Path, meta.Rev, fw)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// TarFolder writes a tar archive of the folder at path and everything inside
+// it to w, with names relative to the folder, compressing it with gzip if
+// compress is true. Like ZipFolder, files are streamed one at a time, so the
+// archive can be piped directly into storage.
+func (c *Client) TarFolder(path string, w io.Writer, compress bool) error {
+	var gw *gzip.Writer
+	if compress {
+		gw = gzip.NewWriter(w)
+		w = gw
+	}
+	tw := tar.NewWriter(w)
+	err := c.Walk(path, func(meta *Metadata) error {
+		header := &tar.Header{
+			Name:    archivePath(path, meta.Path),
+			ModTime: meta.modTime(),
+		}
+		if meta.IsDir {
+			header.Typeflag = tar.TypeDir
+			header.Name += "/"
+			header.Mode = 0755
+			return tw.WriteHeader(header)
+		}
+		header.Typeflag = tar.TypeReg
+		header.Mode = 0644
+		header.Size = meta.SizeBytes()
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		_, _, err := c.Download(meta.Path, meta.Rev, tw)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if gw != nil {
+		return gw.Close()
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package dropbox
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// A BatchResult is the outcome of one entry of a batch operation. If the
+// operation failed for the entry, Error describes why and Metadata is nil.
+type BatchResult struct {
+	Metadata *FileMetadata
+	Error    string
+}
+
+// A JobPoller waits for version 2 asynchronous jobs to complete, checking their
+// status with exponential backoff, starting at InitialInterval and doubling up
+// to MaxInterval. If Timeout is not zero, waiting gives up with ErrJobTimeout
+// after that long.
+type JobPoller struct {
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	Timeout         time.Duration
+}
+
+// DefaultJobPoller is used by sessions which don't set a JobPoller.
+var DefaultJobPoller = &JobPoller{
+	InitialInterval: 500 * time.Millisecond,
+	MaxInterval:     10 * time.Second,
+}
+
+// ErrJobTimeout is returned when an asynchronous job doesn't complete within
+// its JobPoller's Timeout.
+var ErrJobTimeout = errors.New("timed out waiting for batch job")
+
+func (c *Client) jobPoller() *JobPoller {
+	if c.JobPoller != nil {
+		return c.JobPoller
+	}
+	return DefaultJobPoller
+}
+
+type asyncJobArg struct {
+	AsyncJobID string `json:"async_job_id"`
+}
+
+// asyncJob is the status of a version 2 asynchronous batch job.
+type asyncJob struct {
+	Tag        string            `json:".tag"`
+	AsyncJobID string            `json:"async_job_id"`
+	Entries    []json.RawMessage `json:"entries"`
+}
+
+// wait polls the check endpoint until the job completes.
+func (jp *JobPoller) wait(c *Client, checkEndpoint string, job *asyncJob) error {
+	start := time.Now()
+	interval := jp.InitialInterval
+	id := job.AsyncJobID
+	for job.Tag == "async_job_id" || job.Tag == "in_progress" {
+		if jp.Timeout > 0 && time.Since(start)+interval > jp.Timeout {
+			return ErrJobTimeout
+		}
+		time.Sleep(interval)
+		if interval *= 2; jp.MaxInterval > 0 && interval > jp.MaxInterval {
+			interval = jp.MaxInterval
+		}
+		if err := c.rpc(checkEndpoint, asyncJobArg{id}, job); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runBatch launches a batch job and waits for its results.
+func (c *Client) runBatch(endpoint, checkEndpoint string, arg interface{}) ([]BatchResult, error) {
+	var job asyncJob
+	if err := c.rpc(endpoint, arg, &job); err != nil {
+		return nil, err
+	}
+	if err := c.jobPoller().wait(c, checkEndpoint, &job); err != nil {
+		return nil, err
+	}
+	return job.results()
+}
+
+// results converts the entries of a complete job into BatchResults.
+func (j *asyncJob) results() ([]BatchResult, error) {
+	if j.Tag != "complete" {
+		return nil, errors.New("batch job " + j.Tag)
+	}
+	results := make([]BatchResult, len(j.Entries))
+	for i, raw := range j.Entries {
+		var entry struct {
+			Tag      string        `json:".tag"`
+			Metadata *FileMetadata `json:"metadata"`
+			Success  *FileMetadata `json:"success"`
+			Failure  *struct {
+				Tag string `json:".tag"`
+			} `json:"failure"`
+		}
+		if err := json.Unmarshal(raw, &entry); err != nil {
+			return nil, err
+		}
+		switch {
+		case entry.Tag != "success":
+			results[i].Error = entry.Tag
+			if entry.Failure != nil {
+				results[i].Error = entry.Failure.Tag
+			}
+		case entry.Metadata != nil:
+			results[i].Metadata = entry.Metadata
+		case entry.Success != nil:
+			results[i].Metadata = entry.Success
+		default:
+			// Some batches return the metadata inline in the entry.
+			var meta FileMetadata
+			if err := json.Unmarshal(raw, &meta); err != nil {
+				return nil, err
+			}
+			meta.Tag = "file"
+			results[i].Metadata = &meta
+		}
+	}
+	return results, nil
+}
+
+// A RelocationPath is a source and destination pair for CopyBatch and MoveBatch.
+type RelocationPath struct {
+	FromPath string `json:"from_path"`
+	ToPath   string `json:"to_path"`
+}
+
+func relocationArg(pairs []RelocationPath, autorename bool) interface{} {
+	entries := make([]RelocationPath, len(pairs))
+	for i, p := range pairs {
+		entries[i] = RelocationPath{v2Path(p.FromPath), v2Path(p.ToPath)}
+	}
+	return struct {
+		Entries    []RelocationPath `json:"entries"`
+		Autorename bool             `json:"autorename"`
+	}{entries, autorename}
+}
+
+// CopyBatch copies many files and folders at once, waiting for the job to
+// complete, and returns the result of each copy, in order. If autorename is
+// true, conflicting destinations are renamed rather than failing.
+func (c *Client) CopyBatch(pairs []RelocationPath, autorename bool) ([]BatchResult, error) {
+	return c.runBatch("/files/copy_batch_v2", "/files/copy_batch/check_v2", relocationArg(pairs, autorename))
+}
+
+// MoveBatch moves many files and folders at once, waiting for the job to
+// complete, and returns the result of each move, in order. If autorename is
+// true, conflicting destinations are renamed rather than failing.
+func (c *Client) MoveBatch(pairs []RelocationPath, autorename bool) ([]BatchResult, error) {
+	return c.runBatch("/files/move_batch_v2", "/files/move_batch/check_v2", relocationArg(pairs, autorename))
+}
+
+// DeleteBatch deletes many files and folders at once, waiting for the job to
+// complete, and returns the result of each deletion, in order.
+func (c *Client) DeleteBatch(paths ...string) ([]BatchResult, error) {
+	type deleteArg struct {
+		Path string `json:"path"`
+	}
+	arg := struct {
+		Entries []deleteArg `json:"entries"`
+	}{make([]deleteArg, len(paths))}
+	for i, p := range paths {
+		arg.Entries[i].Path = v2Path(p)
+	}
+	return c.runBatch("/files/delete_batch", "/files/delete_batch/check", arg)
+}
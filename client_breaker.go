@@ -0,0 +1,81 @@
+package dropbox
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without contacting Dropbox while a
+// CircuitBreaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// A CircuitBreaker stops sending requests after Threshold consecutive calls
+// have failed with a network error or a server error (5xx status), failing
+// fast with ErrCircuitOpen instead. After Cooldown a single trial call is let
+// through: if it succeeds the breaker closes again, otherwise it stays open for
+// another Cooldown. Calls canceled by their caller's context don't count as
+// failures. It is safe for concurrent use, and may be shared by several
+// sessions.
+type CircuitBreaker struct {
+	Threshold int
+	Cooldown  time.Duration
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	trial    bool // a trial call is in progress
+}
+
+// NewCircuitBreaker creates a CircuitBreaker with the given settings.
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{Threshold: threshold, Cooldown: cooldown}
+}
+
+// Open reports whether the breaker is currently rejecting calls.
+func (cb *CircuitBreaker) Open() bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return cb.tripped() && (cb.trial || time.Since(cb.openedAt) < cb.Cooldown)
+}
+
+func (cb *CircuitBreaker) tripped() bool {
+	return cb.Threshold > 0 && cb.failures >= cb.Threshold
+}
+
+// allow reports whether a call may be made.
+func (cb *CircuitBreaker) allow() bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if !cb.tripped() {
+		return true
+	}
+	if cb.trial || time.Since(cb.openedAt) < cb.Cooldown {
+		return false
+	}
+	cb.trial = true
+	return true
+}
+
+// record updates the breaker with the outcome of the call req. Calls ended by
+// their own context, canceled or past its deadline, say nothing about the
+// server and aren't counted.
+func (cb *CircuitBreaker) record(req *http.Request, resp *http.Response, err error) {
+	failed := err != nil || resp.StatusCode >= http.StatusInternalServerError
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.trial = false
+	if failed && req.Context().Err() != nil {
+		return
+	}
+	if !failed {
+		cb.failures = 0
+		return
+	}
+	cb.failures++
+	if cb.tripped() {
+		cb.openedAt = time.Now()
+	}
+}
@@ -0,0 +1,48 @@
+package dropbox
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	cb := NewCircuitBreaker(2, time.Hour)
+	req := httptest.NewRequest("GET", MetadataURL+"/dropbox/a", nil)
+	fail := errors.New("connection reset")
+
+	cb.record(req, nil, fail)
+	cb.record(req, &http.Response{StatusCode: http.StatusOK}, nil)
+	cb.record(req, nil, fail)
+	if cb.Open() {
+		t.Fatal("breaker opened without consecutive failures")
+	}
+	cb.record(req, &http.Response{StatusCode: http.StatusServiceUnavailable}, nil)
+	if !cb.Open() || cb.allow() {
+		t.Fatal("breaker not open after Threshold failures")
+	}
+}
+
+func TestCircuitBreakerIgnoresCanceledCalls(t *testing.T) {
+	cb := NewCircuitBreaker(1, time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest("GET", MetadataURL+"/dropbox/a", nil).WithContext(ctx)
+
+	for i := 0; i < 3; i++ {
+		cb.record(req, nil, ctx.Err())
+	}
+	if cb.Open() {
+		t.Error("canceled calls opened the breaker")
+	}
+
+	deadline, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	cb.record(req.WithContext(deadline), nil, deadline.Err())
+	if cb.Open() {
+		t.Error("calls past their deadline opened the breaker")
+	}
+}
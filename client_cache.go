@@ -0,0 +1,87 @@
+package dropbox
+
+import (
+	"sync"
+)
+
+// A MetadataCache stores folder listings retrieved through a Client, and uses
+// the hash parameter of the metadata call to revalidate them. When a folder has
+// not changed the server replies with 304 Not Modified and the cached listing is
+// returned, making repeated polls of a folder nearly free. It is safe for
+// concurrent use.
+//
+// The Metadata values returned are shared with the cache and must not be
+// modified.
+type MetadataCache struct {
+	client *Client
+
+	mu      sync.Mutex
+	entries map[metadataCacheKey]*Metadata
+}
+
+type metadataCacheKey struct {
+	root    AccessRoot
+	path    string
+	deleted bool
+}
+
+// NewMetadataCache creates an empty cache that performs requests using the
+// given client.
+func NewMetadataCache(c *Client) *MetadataCache {
+	return &MetadataCache{
+		client:  c,
+		entries: make(map[metadataCacheKey]*Metadata),
+	}
+}
+
+func (mc *MetadataCache) key(path string, deleted bool) metadataCacheKey {
+	return metadataCacheKey{mc.client.root, NormalizePath(mc.client.filePath(path)), deleted}
+}
+
+// List returns the metadata, including contents, of the folder at the given
+// path. If deleted is true, deleted files are included in the listing.
+// Listings of files (which have no hash) are never cached.
+func (mc *MetadataCache) List(path string, deleted bool) (*Metadata, error) {
+	key := mc.key(path, deleted)
+
+	mc.mu.Lock()
+	cached := mc.entries[key]
+	mc.mu.Unlock()
+
+	hash := ""
+	if cached != nil {
+		hash = cached.Hash
+	}
+
+	meta, unmodified, err := mc.client.Metadata(path, 0, hash, true, deleted, "")
+	if err != nil {
+		return nil, err
+	}
+	if unmodified && cached != nil {
+		return cached, nil
+	}
+
+	mc.mu.Lock()
+	if meta.IsDir && meta.Hash != "" {
+		mc.entries[key] = meta
+	} else {
+		delete(mc.entries, key)
+	}
+	mc.mu.Unlock()
+	return meta, nil
+}
+
+// Invalidate removes any cached listings of the folder at path.
+func (mc *MetadataCache) Invalidate(path string) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	delete(mc.entries, mc.key(path, false))
+	delete(mc.entries, mc.key(path, true))
+}
+
+// Clear removes all cached listings.
+func (mc *MetadataCache) Clear() {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.entries = make(map[metadataCacheKey]*Metadata)
+}
@@ -0,0 +1,116 @@
+package dropbox
+
+import (
+	"bytes"
+	"sync"
+	"time"
+)
+
+// Defaults of a ChunkTuner.
+const (
+	MinChunkSize       = 256 * 1024       // Smallest chunk size chosen by default
+	MaxChunkSize       = 32 * 1024 * 1024 // Largest chunk size chosen by default
+	InitialChunkSize   = 1024 * 1024      // Chunk size used before any measurement
+	DefaultChunkTarget = 4 * time.Second  // How long a chunk should take to send
+)
+
+// A ChunkTuner chooses the size of the chunks of chunked uploads from their
+// observed performance. Chunks start small, double in size while they are
+// sent much faster than Target, and halve when they are much slower or fail,
+// so uploads make good use of fast links without losing a lot of work on
+// every error over flaky ones. A ChunkTuner is safe for concurrent use, and
+// is best shared by all uploads over the same link.
+type ChunkTuner struct {
+	Min, Max int           // Bounds of the chunk size, MinChunkSize and MaxChunkSize if zero
+	Target   time.Duration // Time a chunk should take, DefaultChunkTarget if zero
+
+	mu   sync.Mutex
+	size int
+}
+
+func (ct *ChunkTuner) bounds() (min, max int) {
+	min, max = ct.Min, ct.Max
+	if min <= 0 {
+		min = MinChunkSize
+	}
+	if max <= 0 {
+		max = MaxChunkSize
+	}
+	if max < min {
+		max = min
+	}
+	return min, max
+}
+
+func (ct *ChunkTuner) clamp(size int) int {
+	min, max := ct.bounds()
+	if size < min {
+		return min
+	}
+	if size > max {
+		return max
+	}
+	return size
+}
+
+// Size returns the size the next chunk should have.
+func (ct *ChunkTuner) Size() int {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	if ct.size == 0 {
+		ct.size = ct.clamp(InitialChunkSize)
+	}
+	return ct.size
+}
+
+// record adjusts the chunk size after a chunk of n bytes took d to send, or
+// failed with err.
+func (ct *ChunkTuner) record(n int, d time.Duration, err error) {
+	size := ct.Size()
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	if err != nil {
+		ct.size = ct.clamp(size / 2)
+		return
+	}
+	if n == 0 || d <= 0 {
+		return
+	}
+	target := ct.Target
+	if target <= 0 {
+		target = DefaultChunkTarget
+	}
+	// The size which would have taken the target time at the rate observed.
+	ideal := float64(n) * float64(target) / float64(d)
+	switch {
+	case ideal >= 2*float64(size):
+		ct.size = ct.clamp(size * 2)
+	case ideal <= float64(size)/2:
+		ct.size = ct.clamp(size / 2)
+	}
+}
+
+// nextChunkSize returns the size of the next chunk of an upload, which is
+// chunkSize if it is positive, otherwise the size chosen by the ChunkTuner of
+// the Session, or DefaultChunkSize.
+func (c *Client) nextChunkSize(chunkSize int) int {
+	if chunkSize > 0 {
+		return chunkSize
+	}
+	if c.ChunkTuner != nil {
+		return c.ChunkTuner.Size()
+	}
+	return DefaultChunkSize
+}
+
+// sendChunk sends a chunk with ChunkedUpload. If tuned is true, its size was
+// chosen by the ChunkTuner of the Session, which is told how it went.
+func (c *Client) sendChunk(state *ChunkedUpload, chunk []byte, tuned bool) (*ChunkedUpload, error) {
+	start := time.Now()
+	next, err := c.ChunkedUpload(state.UploadId, state.Offset, bytes.NewReader(chunk), int64(len(chunk)))
+	if tuned && c.ChunkTuner != nil {
+		c.ChunkTuner.record(len(chunk), time.Since(start), err)
+	}
+	return next, err
+}
@@ -0,0 +1,131 @@
+package dropbox
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// A TreeReport is the result of CompareTree. Paths are relative to the
+// compared folders, use slashes, and are sorted.
+type TreeReport struct {
+	OnlyLocal  []string         // Files only in the local directory
+	OnlyRemote []string         // Files only in the dropbox folder
+	Different  []TreeDifference // Files on both sides which differ
+	Same       int              // Number of files which are the same on both sides
+}
+
+// InSync reports whether both sides hold the same files.
+func (r *TreeReport) InSync() bool {
+	return len(r.OnlyLocal) == 0 && len(r.OnlyRemote) == 0 && len(r.Different) == 0
+}
+
+// A TreeDifference describes a file which differs between the local and
+// remote trees compared by CompareTree. Content is only compared when the
+// sizes are the same, so a file which differs by MTime alone has the same
+// content on both sides.
+type TreeDifference struct {
+	Path                    string
+	LocalSize, RemoteSize   int64
+	LocalMTime, RemoteMTime time.Time
+	Size, Content, MTime    bool // Which of the size, content hash and modification time differ
+}
+
+// CompareTree compares the files below the local directory localDir with those
+// below the folder remotePath, to report which are missing on either side and
+// which differ. Paths are matched ignoring case, as Dropbox does. Files of the
+// same size are compared by content hash, which reads the local file, and
+// modification times are compared with the client_modified time of the remote
+// file, at the one second precision kept by Dropbox. Folders aren't reported,
+// only the files in them, and local files other than regular files are
+// ignored. A missing remote folder is treated as empty.
+func (c *Client) CompareTree(localDir, remotePath string) (*TreeReport, error) {
+	remote := make(map[string]*FileMetadata)
+	entries, _, err := c.ListFolderAll(remotePath, &ListFolderOptions{Recursive: true})
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	root := NormalizePath(remotePath)
+	for i := range entries {
+		e := &entries[i]
+		if e.Tag != "file" {
+			continue
+		}
+		remote[treeRelPath(root, NormalizePath(e.PathLower))] = e
+	}
+
+	report := &TreeReport{}
+	seen := make(map[string]bool, len(remote))
+	err = filepath.Walk(localDir, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(localDir, name)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		key := strings.ToLower(rel)
+		meta, ok := remote[key]
+		if !ok {
+			report.OnlyLocal = append(report.OnlyLocal, rel)
+			return nil
+		}
+		seen[key] = true
+
+		d := TreeDifference{
+			Path:        rel,
+			LocalSize:   info.Size(),
+			RemoteSize:  meta.Size,
+			LocalMTime:  info.ModTime(),
+			RemoteMTime: meta.ClientModified,
+		}
+		d.Size = d.LocalSize != d.RemoteSize
+		d.MTime = d.LocalMTime.Unix() != d.RemoteMTime.Unix()
+		if !d.Size && meta.ContentHash != "" {
+			hash, err := ContentHashFile(name)
+			if err != nil {
+				return err
+			}
+			d.Content = hash != meta.ContentHash
+		}
+		if d.Size || d.Content || d.MTime {
+			report.Different = append(report.Different, d)
+		} else {
+			report.Same++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for key, meta := range remote {
+		if !seen[key] {
+			p := meta.PathDisplay
+			if p == "" {
+				p = meta.PathLower
+			}
+			report.OnlyRemote = append(report.OnlyRemote, treeRelPath(root, p))
+		}
+	}
+	sort.Strings(report.OnlyLocal)
+	sort.Strings(report.OnlyRemote)
+	sort.Slice(report.Different, func(i, j int) bool { return report.Different[i].Path < report.Different[j].Path })
+	return report, nil
+}
+
+// treeRelPath returns p relative to root, a normalized remote path, which p
+// must be below, ignoring case.
+func treeRelPath(root, p string) string {
+	if root == "/" || len(p) < len(root) {
+		return strings.TrimPrefix(p, "/")
+	}
+	return strings.TrimPrefix(p[len(root):], "/")
+}
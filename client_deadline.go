@@ -0,0 +1,79 @@
+package dropbox
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// A DeadlineError is returned by a transfer which didn't complete before the
+// deadline of its client, set with WithTransferDeadline. It matches
+// context.DeadlineExceeded with errors.Is.
+type DeadlineError struct {
+	Path        string
+	Deadline    time.Time
+	Transferred int64 // Bytes downloaded, or uploaded and acknowledged by the server
+	Size        int64 // Size of the file, or -1 if unknown
+	Err         error // Error the transfer stopped with
+}
+
+func (e *DeadlineError) Error() string {
+	if e.Size >= 0 {
+		return fmt.Sprintf("transfer of %s missed its deadline after %d of %d bytes: %v", e.Path, e.Transferred, e.Size, e.Err)
+	}
+	return fmt.Sprintf("transfer of %s missed its deadline after %d bytes: %v", e.Path, e.Transferred, e.Err)
+}
+
+// Unwrap returns the error the transfer stopped with.
+func (e *DeadlineError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is context.DeadlineExceeded.
+func (e *DeadlineError) Is(target error) bool {
+	return target == context.DeadlineExceeded
+}
+
+// WithTransferDeadline returns a copy of the client whose uploads and downloads
+// must complete by deadline, eg: a whole upload of many chunks, which is
+// independent of the Session's Timeout limiting each request. This applies to
+// Upload, UploadFromPath, ChunkedPutFile, Download and DownloadToPath, which
+// fail with a *DeadlineError once the deadline passes. A zero deadline
+// removes the limit.
+func (c *Client) WithTransferDeadline(deadline time.Time) *Client {
+	clone := *c
+	clone.deadline = deadline
+	return &clone
+}
+
+// beginTransfer returns a copy of the client whose requests are abandoned at
+// its transfer deadline, and a function releasing the resources of the copy.
+// Without a deadline, the client itself is returned.
+func (c *Client) beginTransfer() (*Client, context.CancelFunc) {
+	if c.deadline.IsZero() {
+		return c, func() {}
+	}
+	ctx := c.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithDeadline(ctx, c.deadline)
+	return c.WithContext(ctx), cancel
+}
+
+// transferError returns err, the error a transfer of path failed with after
+// n of size bytes, as a *DeadlineError if the transfer deadline has passed.
+func (c *Client) transferError(path string, n, size int64, err error) error {
+	if err == nil || c.deadline.IsZero() || time.Now().Before(c.deadline) {
+		return err
+	}
+	return &DeadlineError{Path: path, Deadline: c.deadline, Transferred: n, Size: size, Err: err}
+}
+
+// metaSize returns the size of the file described by meta, or -1 if it is nil.
+func metaSize(meta *Metadata) int64 {
+	if meta == nil {
+		return -1
+	}
+	return meta.Bytes
+}
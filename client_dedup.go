@@ -0,0 +1,116 @@
+package dropbox
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+)
+
+// Prefix of the keys used by a DedupUploader in its KV.
+const copyRefKey = "copyref:"
+
+// A DedupUploader avoids uploading content which already exists in the
+// dropbox. It remembers a copy ref for the SHA-256 hash of everything it
+// uploads, in a KV, and satisfies later uploads of the same content with a
+// server side copy instead of transferring the data again. It is safe for
+// concurrent use if its KV is.
+type DedupUploader struct {
+	// OnError, if set, is called when the copy ref of an uploaded file
+	// can't be remembered. The upload itself succeeded, but later uploads
+	// of the same content won't be copied from it.
+	OnError func(path string, err error)
+
+	client *Client
+	kv     KV
+}
+
+// NewDedupUploader creates a DedupUploader which performs requests using c and
+// stores copy refs in kv.
+func NewDedupUploader(c *Client, kv KV) *DedupUploader {
+	return &DedupUploader{client: c, kv: kv}
+}
+
+// hashContent returns the hex SHA-256 hash of data, which is rewound.
+func hashContent(data io.ReadSeeker) (string, error) {
+	h := sha256.New()
+	if _, err := copyBuffered(h, data); err != nil {
+		return "", err
+	}
+	if _, err := data.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Upload uploads data to path, as Client.Upload does. If content with the same
+// hash was seen before and its copy ref is still valid, the file is copied
+// from it instead, and copied is true. Since copies can't replace files, the
+// data is uploaded if the copy fails for any reason. Failing to remember the
+// copy ref of an upload doesn't make it fail, it is reported to OnError.
+func (du *DedupUploader) Upload(path string, data io.ReadSeeker, opts *UploadOptions) (meta *Metadata, copied bool, err error) {
+	hash, err := hashContent(data)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if ref, ok := du.lookup(hash); ok {
+		meta, err := du.client.Copy(path, "", ref)
+		if err == nil {
+			return meta, true, nil
+		}
+		if errors.Is(err, ErrNotFound) {
+			du.kv.Delete(copyRefKey + hash)
+		}
+	}
+
+	meta, err = du.client.Upload(path, data, opts)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := du.register(hash, meta.Path); err != nil && du.OnError != nil {
+		du.OnError(meta.Path, err)
+	}
+	return meta, false, nil
+}
+
+// Remember records that the file at path has the content of data, so it can
+// be copied by later uploads of the same content.
+func (du *DedupUploader) Remember(path string, data io.ReadSeeker) error {
+	hash, err := hashContent(data)
+	if err != nil {
+		return err
+	}
+	return du.register(hash, path)
+}
+
+func (du *DedupUploader) register(hash, path string) error {
+	ref, err := du.client.CopyRef(path)
+	if err != nil {
+		return err
+	}
+	value, err := json.Marshal(ref)
+	if err != nil {
+		return err
+	}
+	return du.kv.Put(copyRefKey+hash, value)
+}
+
+// lookup returns an unexpired copy ref for content with the given hash.
+func (du *DedupUploader) lookup(hash string) (string, bool) {
+	value, ok, err := du.kv.Get(copyRefKey + hash)
+	if err != nil || !ok {
+		return "", false
+	}
+	var ref CopyRef
+	if err := json.Unmarshal(value, &ref); err != nil {
+		return "", false
+	}
+	if !ref.Expires.IsZero() && time.Now().After(ref.Expires.Time) {
+		du.kv.Delete(copyRefKey + hash)
+		return "", false
+	}
+	return ref.CopyRef, true
+}
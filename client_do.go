@@ -0,0 +1,90 @@
+package dropbox
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// Do sends an API request and returns the raw response, for calls this package
+// doesn't support or responses which the caller wants to process itself. The
+// request is authorized, its URL is redirected according to the BaseURLs of
+// the Session, and the locale of the Session is added to params unless they
+// already set one. If method is POST and body is nil, params are sent as a
+// form, otherwise they are sent in the URL.
+//
+// As with the other calls, a rejected access token is returned as an
+// *AuthorizationError. Other error statuses are returned as responses, and it
+// is up to the caller to check the status code and to close the body.
+func (c *Client) Do(method, urlStr string, params url.Values, body io.Reader) (*http.Response, error) {
+	return c.do(method, urlStr, c.withLocale(params), body, 0)
+}
+
+// withLocale returns a copy of params with the locale of the Session added,
+// unless params set one.
+func (c *Client) withLocale(params url.Values) url.Values {
+	p := c.makeParams(true)
+	for k, v := range params {
+		p[k] = append([]string(nil), v...)
+	}
+	return p
+}
+
+// A RequestBuilder gives access to the pieces the Client's own calls are made
+// of, so new endpoints can be implemented outside this package the same way:
+//
+//	b := c.RequestBuilder()
+//	params := b.Params(true)
+//	params.Set("list", "true")
+//	req, err := b.NewRequest("GET", dropbox.MetadataURL+b.FilePath(path), params, nil)
+//	...
+//	resp, err := b.Send(req)
+//	...
+//	err = b.DecodeJSON(resp, &meta)
+type RequestBuilder struct {
+	c *Client
+}
+
+// RequestBuilder returns a RequestBuilder for requests made as c.
+func (c *Client) RequestBuilder() *RequestBuilder {
+	return &RequestBuilder{c}
+}
+
+// FilePath returns the path p inside the access root of the Client, as it is
+// appended to the URL of file calls.
+func (b *RequestBuilder) FilePath(p string) string {
+	return b.c.filePath(p)
+}
+
+// Params returns new parameters for a call, containing the locale of the
+// Session if locale is true.
+func (b *RequestBuilder) Params(locale bool) url.Values {
+	return b.c.makeParams(locale)
+}
+
+// NewRequest builds an authorized request. Params are signed along with the
+// request, so they must not be changed afterwards. If method is POST and body
+// is nil, params are sent as a form, otherwise they are sent in the URL. The
+// content length is taken from body if it is a bytes.Buffer, bytes.Reader or
+// strings.Reader, and may be set on the request for other readers.
+func (b *RequestBuilder) NewRequest(method, urlStr string, params url.Values, body io.Reader) (*http.Request, error) {
+	if params == nil {
+		params = make(url.Values)
+	}
+	return b.c.newRequest(method, urlStr, params, body, 0)
+}
+
+// Send sends a request built by NewRequest, subject to the rate limits, retry
+// policy and circuit breaker of the Client. A rejected access token is
+// returned as an *AuthorizationError.
+func (b *RequestBuilder) Send(req *http.Request) (*http.Response, error) {
+	return b.c.doRequest(req)
+}
+
+// DecodeJSON decodes the JSON body of a successful response into target,
+// according to the JSONMode of the Session, and closes it. Error responses
+// are returned as an *APIError.
+func (b *RequestBuilder) DecodeJSON(resp *http.Response, target interface{}) error {
+	defer drainAndClose(resp.Body)
+	return b.c.decodeJSON(resp, target)
+}
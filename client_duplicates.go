@@ -0,0 +1,187 @@
+package dropbox
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+)
+
+// DuplicatePrefixSize is the number of bytes FindDuplicates reads from the
+// start of files it has to download, to tell apart files of the same size
+// before downloading them whole.
+const DuplicatePrefixSize = 64 * 1024
+
+// DuplicateOptions control FindDuplicates. The zero value considers all
+// non-empty files and downloads nothing.
+type DuplicateOptions struct {
+	// MinSize is the size of the smallest files considered.
+	MinSize int64
+
+	// DownloadBudget is the number of bytes which may be downloaded to hash
+	// files Dropbox reports no content hash for. Files which would exceed
+	// it are reported as unchecked.
+	DownloadBudget int64
+}
+
+// A DuplicateSet is a group of files with the same content.
+type DuplicateSet struct {
+	Size        int64
+	ContentHash string
+	Paths       []string // Sorted
+}
+
+// Reclaimable returns the number of bytes saved by keeping a single copy.
+func (s *DuplicateSet) Reclaimable() int64 {
+	return s.Size * int64(len(s.Paths)-1)
+}
+
+// A DuplicateReport is the result of FindDuplicates.
+type DuplicateReport struct {
+	Sets        []DuplicateSet // Largest Reclaimable first
+	Reclaimable int64          // Total of the Reclaimable bytes of the sets
+	Unchecked   []string       // Files which may have duplicates, but couldn't be hashed within the budget
+	Downloaded  int64          // Bytes downloaded for hashing
+}
+
+// FindDuplicates reports the files below the folder at path which have the
+// same content. Files are first grouped by size, and only files sharing their
+// size with another are compared, by the content hash Dropbox computes. Files
+// without one are downloaded to hash them, within opts.DownloadBudget: when
+// there are several of a size, the first DuplicatePrefixSize bytes of each are
+// read first, and only those with a common prefix are downloaded whole. opts
+// may be nil.
+func (c *Client) FindDuplicates(path string, opts *DuplicateOptions) (*DuplicateReport, error) {
+	if opts == nil {
+		opts = &DuplicateOptions{}
+	}
+	entries, _, err := c.ListFolderAll(path, &ListFolderOptions{Recursive: true})
+	if err != nil {
+		return nil, err
+	}
+	bySize := make(map[int64][]*FileMetadata)
+	for i := range entries {
+		e := &entries[i]
+		if e.Tag == "file" && e.Size > 0 && e.Size >= opts.MinSize {
+			bySize[e.Size] = append(bySize[e.Size], e)
+		}
+	}
+
+	f := &duplicateFinder{client: c, budget: opts.DownloadBudget, report: &DuplicateReport{}}
+	for size, files := range bySize {
+		if len(files) < 2 {
+			continue
+		}
+		if err := f.compare(size, files); err != nil {
+			return nil, err
+		}
+	}
+
+	r := f.report
+	sort.Slice(r.Sets, func(i, j int) bool {
+		a, b := &r.Sets[i], &r.Sets[j]
+		if a.Reclaimable() != b.Reclaimable() {
+			return a.Reclaimable() > b.Reclaimable()
+		}
+		return a.Paths[0] < b.Paths[0]
+	})
+	sort.Strings(r.Unchecked)
+	return r, nil
+}
+
+// A duplicateFinder compares the files of each size for FindDuplicates.
+type duplicateFinder struct {
+	client *Client
+	budget int64
+	report *DuplicateReport
+}
+
+// compare adds the sets of duplicates among files, all of the given size.
+func (f *duplicateFinder) compare(size int64, files []*FileMetadata) error {
+	byHash := make(map[string][]string)
+	var unhashed []*FileMetadata
+	for _, e := range files {
+		if e.ContentHash != "" {
+			byHash[e.ContentHash] = append(byHash[e.ContentHash], e.PathDisplay)
+		} else {
+			unhashed = append(unhashed, e)
+		}
+	}
+
+	// Prefixes can only rule out files when none of the size could be
+	// compared by the hashes Dropbox reported.
+	if len(byHash) == 0 && size > DuplicatePrefixSize {
+		var err error
+		if unhashed, err = f.samePrefix(unhashed); err != nil {
+			return err
+		}
+	}
+	for _, e := range unhashed {
+		if !f.take(size) {
+			f.report.Unchecked = append(f.report.Unchecked, e.PathDisplay)
+			continue
+		}
+		body, _, err := f.client.GetFile(e.PathDisplay, e.Rev)
+		if err != nil {
+			return err
+		}
+		hash, err := ContentHash(body)
+		body.Close()
+		if err != nil {
+			return err
+		}
+		byHash[hash] = append(byHash[hash], e.PathDisplay)
+	}
+
+	for hash, paths := range byHash {
+		if len(paths) < 2 {
+			continue
+		}
+		sort.Strings(paths)
+		set := DuplicateSet{Size: size, ContentHash: hash, Paths: paths}
+		f.report.Sets = append(f.report.Sets, set)
+		f.report.Reclaimable += set.Reclaimable()
+	}
+	return nil
+}
+
+// samePrefix returns the files whose first DuplicatePrefixSize bytes are the
+// same as those of another of files. Files which can't be read within the
+// budget are reported as unchecked.
+func (f *duplicateFinder) samePrefix(files []*FileMetadata) ([]*FileMetadata, error) {
+	byPrefix := make(map[string][]*FileMetadata)
+	for _, e := range files {
+		if !f.take(DuplicatePrefixSize) {
+			f.report.Unchecked = append(f.report.Unchecked, e.PathDisplay)
+			continue
+		}
+		body, _, err := f.client.GetFileRange(e.PathDisplay, e.Rev, 0, DuplicatePrefixSize)
+		if err != nil {
+			return nil, err
+		}
+		h := sha256.New()
+		_, err = copyBuffered(h, body)
+		body.Close()
+		if err != nil {
+			return nil, err
+		}
+		key := hex.EncodeToString(h.Sum(nil))
+		byPrefix[key] = append(byPrefix[key], e)
+	}
+	var same []*FileMetadata
+	for _, group := range byPrefix {
+		if len(group) > 1 {
+			same = append(same, group...)
+		}
+	}
+	return same, nil
+}
+
+// take charges n bytes to the download budget, if it allows them.
+func (f *duplicateFinder) take(n int64) bool {
+	if n > f.budget {
+		return false
+	}
+	f.budget -= n
+	f.report.Downloaded += n
+	return true
+}
@@ -0,0 +1,327 @@
+package dropbox
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+)
+
+// Parameters of the format written by an EncryptedClient. Files start with a
+// header made of encryptedMagic, the format version, the segment size and a
+// random nonce prefix. The content follows as a sequence of segments, each
+// encrypted and authenticated separately with AES-GCM, so files can be
+// streamed without being held in memory. The header is authenticated with
+// every segment, and the last segment is marked, so a truncated file is
+// detected.
+const (
+	EncryptedSegmentSize = 64 << 10
+
+	encryptedMagic      = "DBXENC"
+	encryptedVersion    = 1
+	encryptedPrefixSize = 7
+	encryptedHeaderSize = len(encryptedMagic) + 1 + 4 + encryptedPrefixSize
+	maxSegmentSize      = 16 << 20
+)
+
+// ErrBadEncryptedData is returned when encrypted content or names cannot be
+// decrypted, either because the data is corrupt or the key is wrong.
+var ErrBadEncryptedData = errors.New("invalid or corrupt encrypted data")
+
+// names are encoded in lower-case base32, since Dropbox paths are not case
+// sensitive.
+var nameEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// An EncryptedClient stores files in a dropbox encrypted with a key known only
+// to the application, so Dropbox never sees their content. If EncryptNames is
+// set, the names of files and folders are encrypted too. Name encryption is
+// deterministic, so files can still be looked up by path, but it limits names
+// to about 130 bytes.
+//
+// Only the methods of EncryptedClient encrypt and decrypt. Files written with
+// the underlying Client are stored as they are.
+type EncryptedClient struct {
+	EncryptNames bool
+
+	client     *Client
+	contentKey []byte
+	nameKey    []byte // encrypts names
+	nonceKey   []byte // derives the nonces of names from them
+}
+
+// NewEncryptedClient creates an EncryptedClient which performs requests using
+// c and encrypts with keys derived from key, which must be at least 16 bytes
+// long.
+func NewEncryptedClient(c *Client, key []byte, encryptNames bool) (*EncryptedClient, error) {
+	if len(key) < 16 {
+		return nil, errors.New("encryption key must be at least 16 bytes")
+	}
+	return &EncryptedClient{
+		EncryptNames: encryptNames,
+		client:       c,
+		contentKey:   deriveKey(key, "dropbox content"),
+		nameKey:      deriveKey(key, "dropbox names"),
+		nonceKey:     deriveKey(key, "dropbox name nonces"),
+	}, nil
+}
+
+func deriveKey(key []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
+// EncryptPath returns the path a file is stored at in the dropbox. If names
+// aren't encrypted, it is p itself.
+func (ec *EncryptedClient) EncryptPath(p string) string {
+	if !ec.EncryptNames {
+		return p
+	}
+	aead, _ := newGCM(ec.nameKey)
+	names := strings.Split(p, "/")
+	for i, name := range names {
+		if name == "" {
+			continue
+		}
+		mac := hmac.New(sha256.New, ec.nonceKey)
+		mac.Write([]byte(name))
+		nonce := mac.Sum(nil)[:aead.NonceSize()]
+		sealed := aead.Seal(append([]byte{}, nonce...), nonce, []byte(name), nil)
+		names[i] = strings.ToLower(nameEncoding.EncodeToString(sealed))
+	}
+	return strings.Join(names, "/")
+}
+
+// DecryptPath returns the path of a file given the path it is stored at.
+func (ec *EncryptedClient) DecryptPath(p string) (string, error) {
+	if !ec.EncryptNames {
+		return p, nil
+	}
+	aead, _ := newGCM(ec.nameKey)
+	names := strings.Split(p, "/")
+	for i, name := range names {
+		if name == "" {
+			continue
+		}
+		sealed, err := nameEncoding.DecodeString(strings.ToUpper(name))
+		if err != nil || len(sealed) < aead.NonceSize() {
+			return "", ErrBadEncryptedData
+		}
+		plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+		if err != nil {
+			return "", ErrBadEncryptedData
+		}
+		names[i] = string(plain)
+	}
+	return strings.Join(names, "/"), nil
+}
+
+// decryptMetadata replaces the stored path and size in meta with the original
+// ones.
+func (ec *EncryptedClient) decryptMetadata(meta *Metadata) error {
+	p, err := ec.DecryptPath(meta.Path)
+	if err != nil {
+		return err
+	}
+	meta.Path = p
+	if !meta.IsDir {
+		meta.Bytes = plainSize(meta.Bytes)
+		meta.Size = FormatSize(meta.Bytes)
+	}
+	return nil
+}
+
+// plainSize returns the size of the content of an encrypted file of n bytes.
+func plainSize(n int64) int64 {
+	m := n - int64(encryptedHeaderSize)
+	if m < 0 {
+		return 0
+	}
+	const segment = EncryptedSegmentSize + 16
+	segments := (m + segment - 1) / segment
+	if segments == 0 {
+		segments = 1
+	}
+	if size := m - 16*segments; size > 0 {
+		return size
+	}
+	return 0
+}
+
+// Upload encrypts data and uploads it to path, as Client.Upload does.
+func (ec *EncryptedClient) Upload(path string, data io.Reader, opts *UploadOptions) (*Metadata, error) {
+	header := make([]byte, encryptedHeaderSize)
+	n := copy(header, encryptedMagic)
+	header[n] = encryptedVersion
+	binary.BigEndian.PutUint32(header[n+1:], EncryptedSegmentSize)
+	if _, err := io.ReadFull(rand.Reader, header[n+5:]); err != nil {
+		return nil, err
+	}
+	sr, err := ec.newSegmentReader(data, header, false)
+	if err != nil {
+		return nil, err
+	}
+
+	meta, err := ec.client.Upload(ec.EncryptPath(path), io.MultiReader(bytes.NewReader(header), sr), opts)
+	if err != nil {
+		return nil, err
+	}
+	return meta, ec.decryptMetadata(meta)
+}
+
+// GetFile downloads and decrypts the file at path (and revision if rev is not
+// the empty string), as Client.GetFile does. Corruption is reported by the
+// returned reader as ErrBadEncryptedData.
+func (ec *EncryptedClient) GetFile(path, rev string) (io.ReadCloser, *Metadata, error) {
+	body, meta, err := ec.client.GetFile(ec.EncryptPath(path), rev)
+	if err != nil {
+		return nil, nil, err
+	}
+	if meta != nil {
+		if err := ec.decryptMetadata(meta); err != nil {
+			body.Close()
+			return nil, nil, err
+		}
+	}
+
+	header := make([]byte, encryptedHeaderSize)
+	if _, err := io.ReadFull(body, header); err != nil {
+		body.Close()
+		return nil, nil, ErrBadEncryptedData
+	}
+	n := len(encryptedMagic)
+	size := binary.BigEndian.Uint32(header[n+1:])
+	if string(header[:n]) != encryptedMagic || header[n] != encryptedVersion || size == 0 || size > maxSegmentSize {
+		body.Close()
+		return nil, nil, ErrBadEncryptedData
+	}
+	sr, err := ec.newSegmentReader(body, header, true)
+	if err != nil {
+		body.Close()
+		return nil, nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{sr, body}, meta, nil
+}
+
+// Download writes the decrypted content of the file at path (and revision if
+// rev is not the empty string) to w, as Client.Download does.
+func (ec *EncryptedClient) Download(path, rev string, w io.Writer) (int64, *Metadata, error) {
+	body, meta, err := ec.GetFile(path, rev)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer drainAndClose(body)
+
+	n, err := copyBuffered(w, body)
+	return n, meta, err
+}
+
+// ReadDir returns the metadata of the entries of the folder at path, with
+// their original names and sizes. Entries whose names can't be decrypted,
+// which weren't written by an EncryptedClient with the same key, are left out.
+func (ec *EncryptedClient) ReadDir(path string) ([]Metadata, error) {
+	meta, err := ec.client.ListLargeFolder(ec.EncryptPath(path))
+	if err != nil {
+		return nil, err
+	}
+	contents := make([]Metadata, 0, len(meta.Contents))
+	for _, entry := range meta.Contents {
+		if ec.decryptMetadata(&entry) == nil {
+			contents = append(contents, entry)
+		}
+	}
+	return contents, nil
+}
+
+// A segmentReader encrypts or decrypts a stream segment by segment.
+type segmentReader struct {
+	src     *bufio.Reader
+	aead    cipher.AEAD
+	header  []byte
+	in      int // size of an input segment
+	decrypt bool
+	counter uint32
+	done    bool
+	buf     []byte
+	err     error
+}
+
+func (ec *EncryptedClient) newSegmentReader(r io.Reader, header []byte, decrypt bool) (*segmentReader, error) {
+	aead, err := newGCM(ec.contentKey)
+	if err != nil {
+		return nil, err
+	}
+	n := len(encryptedMagic)
+	in := int(binary.BigEndian.Uint32(header[n+1:]))
+	if decrypt {
+		in += aead.Overhead()
+	}
+	return &segmentReader{
+		src:     bufio.NewReaderSize(r, in+1),
+		aead:    aead,
+		header:  header,
+		in:      in,
+		decrypt: decrypt,
+	}, nil
+}
+
+func (sr *segmentReader) Read(p []byte) (int, error) {
+	for len(sr.buf) == 0 {
+		if sr.err != nil {
+			return 0, sr.err
+		}
+		sr.err = sr.next()
+	}
+	n := copy(p, sr.buf)
+	sr.buf = sr.buf[n:]
+	return n, nil
+}
+
+// next processes the next segment. A segment is the last one when it is not
+// followed by more data.
+func (sr *segmentReader) next() error {
+	if sr.done {
+		return io.EOF
+	}
+	data, err := sr.src.Peek(sr.in + 1)
+	final := len(data) <= sr.in
+	if final && err != io.EOF {
+		return err
+	}
+	if !final {
+		data = data[:sr.in]
+	}
+
+	nonce := make([]byte, sr.aead.NonceSize())
+	copy(nonce, sr.header[len(sr.header)-encryptedPrefixSize:])
+	binary.BigEndian.PutUint32(nonce[encryptedPrefixSize:], sr.counter)
+	if final {
+		nonce[len(nonce)-1] = 1
+	}
+	if sr.decrypt {
+		sr.buf, err = sr.aead.Open(nil, nonce, data, sr.header)
+		if err != nil {
+			return ErrBadEncryptedData
+		}
+	} else {
+		sr.buf = sr.aead.Seal(nil, nonce, data, sr.header)
+	}
+	sr.src.Discard(len(data))
+
+	sr.counter++
+	if sr.counter == 0 && !final {
+		return errors.New("encrypted stream too long")
+	}
+	sr.done = final
+	return nil
+}
@@ -16,6 +16,7 @@ func (c *Client) Copy(toPath, fromPath, fromCopyRef string) (meta *Metadata, err
 	}
 
 	err = c.postFormJSON(FileOpsCopyURL, params, &meta)
+	c.emitResult(EntryCopied, toPath, fromPath, meta, err)
 	return
 }
 
@@ -25,6 +26,7 @@ func (c *Client) CreateFolder(path string) (meta *Metadata, err error) {
 	params.Set("path", path)
 	params.Set("root", string(c.root))
 	err = c.postFormJSON(FileOpsCreateFolderURL, params, &meta)
+	c.emitResult(EntryCreated, path, "", meta, err)
 	return
 }
 
@@ -34,6 +36,7 @@ func (c *Client) Delete(path string) (meta *Metadata, err error) {
 	params.Set("path", path)
 	params.Set("root", string(c.root))
 	err = c.postFormJSON(FileOpsDeleteURL, params, &meta)
+	c.emitResult(EntryDeleted, path, "", meta, err)
 	return
 }
 
@@ -46,5 +49,6 @@ func (c *Client) Move(toPath, fromPath string) (meta *Metadata, err error) {
 	params.Set("root", string(c.root))
 
 	err = c.postFormJSON(FileOpsMoveURL, params, &meta)
+	c.emitResult(EntryMoved, toPath, fromPath, meta, err)
 	return
 }
@@ -0,0 +1,30 @@
+//go:build go1.18
+
+package dropbox
+
+import "net/url"
+
+// CallAs performs a GET request to the given version 1 endpoint, and decodes
+// the JSON response into a value of type T. The locale of the Session is
+// added to params unless they set one. Version 2 endpoints are called with
+// RPCAs instead.
+//
+//	uri := dropbox.MetadataURL + c.RequestBuilder().FilePath("/photos")
+//	meta, err := dropbox.CallAs[dropbox.Metadata](c, uri, nil)
+func CallAs[T any](c *Client, endpoint string, params url.Values) (T, error) {
+	var v T
+	err := c.getJSON(endpoint, c.withLocale(params), &v)
+	return v, err
+}
+
+// RPCAs calls the given version 2 RPC endpoint, sending arg as the JSON body
+// of a POST request, and decodes the JSON result into a value of type T. A
+// nil arg sends null, as endpoints without arguments expect.
+//
+//	arg := map[string]string{"path": "/photos"}
+//	meta, err := dropbox.RPCAs[dropbox.FileMetadata](c, "/files/get_metadata", arg)
+func RPCAs[T any](c *Client, endpoint string, arg interface{}) (T, error) {
+	var v T
+	err := c.rpc(endpoint, arg, &v)
+	return v, err
+}
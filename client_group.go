@@ -0,0 +1,81 @@
+package dropbox
+
+import (
+	"context"
+	"sync"
+)
+
+// A BatchOp is an operation run by RunBatch, with a client bound to the
+// context of the batch.
+type BatchOp func(c *Client) error
+
+// RunBatch runs ops concurrently, at most concurrency at a time, or all at
+// once if concurrency is zero or less, failing fast: the first error cancels
+// the context of the client given to the operations running, operations not
+// yet started are skipped, and that error is returned once the running ones
+// have returned. Operations are also stopped when ctx is done.
+func (c *Client) RunBatch(ctx context.Context, concurrency int, ops ...BatchOp) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var once sync.Once
+	var first error
+	c.runOps(ctx, concurrency, ops, func(i int, err error) {
+		once.Do(func() {
+			first = err
+			cancel()
+		})
+	})
+	return first
+}
+
+// RunBatchAll runs ops concurrently like RunBatch, but runs all of them
+// whatever their results, collecting the errors. It returns nil if all of
+// them succeeded, otherwise the error of each operation, at its index in ops.
+// Operations which couldn't start because ctx was done get its error.
+func (c *Client) RunBatchAll(ctx context.Context, concurrency int, ops ...BatchOp) []error {
+	var errs []error
+	var mu sync.Mutex
+	c.runOps(ctx, concurrency, ops, func(i int, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if errs == nil {
+			errs = make([]error, len(ops))
+		}
+		errs[i] = err
+	})
+	return errs
+}
+
+// runOps runs ops with a client bound to ctx, at most concurrency at a time,
+// calling failed with the index and error of each one which fails. Operations
+// not started when ctx is done fail with its error.
+func (c *Client) runOps(ctx context.Context, concurrency int, ops []BatchOp, failed func(i int, err error)) {
+	if concurrency <= 0 || concurrency > len(ops) {
+		concurrency = len(ops)
+	}
+	client := c.WithContext(ctx)
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, op := range ops {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if err := ctx.Err(); err != nil {
+			for j := i; j < len(ops); j++ {
+				failed(j, err)
+			}
+			break
+		}
+		wg.Add(1)
+		go func(i int, op BatchOp) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := op(client); err != nil {
+				failed(i, err)
+			}
+		}(i, op)
+	}
+	wg.Wait()
+}
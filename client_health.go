@@ -0,0 +1,75 @@
+package dropbox
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Health is the result of a connectivity check against the Dropbox servers.
+type Health int
+
+// Possible results of a health check.
+const (
+	Reachable    Health = iota // The servers accepted the request
+	Unauthorized               // The servers rejected the credentials
+	RateLimited                // The servers are rate limiting requests
+	Down                       // The servers could not be reached or failed
+)
+
+var healthNames = []string{"reachable", "unauthorized", "rate-limited", "down"}
+
+func (h Health) String() string {
+	if h >= 0 && int(h) < len(healthNames) {
+		return healthNames[h]
+	}
+	return fmt.Sprintf("Health(%d)", int(h))
+}
+
+// healthOf classifies a status code.
+func healthOf(code int) Health {
+	switch {
+	case code == http.StatusUnauthorized || code == http.StatusForbidden:
+		return Unauthorized
+	case code == http.StatusTooManyRequests || code == http.StatusServiceUnavailable:
+		return RateLimited
+	case code >= 500:
+		return Down
+	}
+	return Reachable
+}
+
+// Ping performs a cheap authenticated request (account/info) and classifies
+// the result, for use in readiness probes. The error describes any failure.
+func (c *Client) Ping() (Health, error) {
+	r, err := c.get(AccountInfoURL, c.makeParams(false))
+	if err != nil {
+		if _, ok := err.(*AuthorizationError); ok {
+			return Unauthorized, err
+		}
+		return Down, err
+	}
+	defer drainAndClose(r.Body)
+
+	var account AccountInfo
+	if err := c.decodeJSON(r, &account); err != nil {
+		return healthOf(r.StatusCode), err
+	}
+	return Reachable, nil
+}
+
+// CheckApp verifies that the app key and secret of the Session are accepted by
+// the server by requesting (and discarding) a request token. Unlike Ping, the
+// Session need not be authorized. Transport failures are reported as Down, and
+// any other failure as Unauthorized.
+func (s *Session) CheckApp() (Health, error) {
+	_, err := s.OauthClient.RequestTemporaryCredentials(s.client(), "", nil)
+	if err == nil {
+		return Reachable, nil
+	}
+	if _, ok := err.(interface {
+		Timeout() bool
+	}); ok {
+		return Down, err
+	}
+	return Unauthorized, err
+}
@@ -0,0 +1,132 @@
+package dropbox
+
+import "time"
+
+// MaxRevisions is the largest rev_limit accepted by the revisions call.
+const MaxRevisions = 1000
+
+// RevisionAsOf returns the metadata of the newest revision of the file at path
+// made at or before t, or nil if the file didn't exist at that time. Only the
+// last MaxRevisions revisions are considered.
+func (c *Client) RevisionAsOf(path string, t time.Time) (*Metadata, error) {
+	revs, err := c.Revisions(path, MaxRevisions)
+	if err != nil {
+		return nil, err
+	}
+	for i := range revs {
+		if !revs[i].Modified.After(t) {
+			if revs[i].IsDeleted {
+				return nil, nil
+			}
+			return &revs[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// RestoreTreeAsOf rolls the files below the folder at path back to the state
+// they were in at t, by restoring each one to its newest revision at or before
+// t. Files deleted since t are brought back. Files which didn't exist at t are
+// left in place, as are folders. The metadata of the restored files is
+// returned, even if an error stops the walk part way.
+func (c *Client) RestoreTreeAsOf(path string, t time.Time) ([]Metadata, error) {
+	var restored []Metadata
+	err := c.restoreTreeAsOf(path, t, &restored)
+	return restored, err
+}
+
+func (c *Client) restoreTreeAsOf(dir string, t time.Time, restored *[]Metadata) error {
+	meta, _, err := c.Metadata(dir, MaxFileLimit, "", true, true, "")
+	if err != nil {
+		return err
+	}
+	for _, entry := range meta.Contents {
+		if entry.IsDir {
+			if err := c.restoreTreeAsOf(entry.Path, t, restored); err != nil {
+				return err
+			}
+			continue
+		}
+		rev, err := c.RevisionAsOf(entry.Path, t)
+		if err != nil {
+			return err
+		}
+		if rev == nil || (rev.Rev == entry.Rev && !entry.IsDeleted) {
+			continue
+		}
+		m, err := c.Restore(entry.Path, rev.Rev)
+		if err != nil {
+			return err
+		}
+		*restored = append(*restored, *m)
+	}
+	return nil
+}
+
+// ListDeleted returns the metadata of the deleted files and folders in the
+// folder at path, and if recursive is true, in all its subfolders, including
+// deleted ones.
+func (c *Client) ListDeleted(path string, recursive bool) ([]Metadata, error) {
+	var deleted []Metadata
+	err := c.listDeleted(path, recursive, &deleted)
+	return deleted, err
+}
+
+func (c *Client) listDeleted(dir string, recursive bool, deleted *[]Metadata) error {
+	meta, _, err := c.Metadata(dir, MaxFileLimit, "", true, true, "")
+	if err != nil {
+		return err
+	}
+	for _, entry := range meta.Contents {
+		if entry.IsDeleted {
+			*deleted = append(*deleted, entry)
+		}
+		if recursive && entry.IsDir {
+			if err := c.listDeleted(entry.Path, true, deleted); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// UndeleteTree restores every deleted file below the folder at path, or the
+// file at path if it is one, to its latest revision from before it was
+// deleted. Deleted folders reappear as the files in them are restored. The
+// metadata of the restored files is returned, even if an error stops the
+// process part way.
+func (c *Client) UndeleteTree(path string) ([]Metadata, error) {
+	meta, _, err := c.Metadata(path, 0, "", false, true, "")
+	if err != nil {
+		return nil, err
+	}
+	targets := []Metadata{*meta}
+	if meta.IsDir {
+		if targets, err = c.ListDeleted(path, true); err != nil {
+			return nil, err
+		}
+	}
+
+	var restored []Metadata
+	for _, entry := range targets {
+		if entry.IsDir || !entry.IsDeleted {
+			continue
+		}
+		revs, err := c.Revisions(entry.Path, MaxRevisions)
+		if err != nil {
+			return restored, err
+		}
+		for _, rev := range revs {
+			if rev.IsDeleted {
+				continue
+			}
+			m, err := c.Restore(entry.Path, rev.Rev)
+			if err != nil {
+				return restored, err
+			}
+			restored = append(restored, *m)
+			break
+		}
+	}
+	return restored, nil
+}
@@ -0,0 +1,218 @@
+package dropbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// A DirIter returns the entries of a folder one at a time, decoding them from
+// the response as they are read, so the listing of a very large folder is
+// never held in memory at once. It must be closed after use.
+//
+//	it, err := c.ReadDirIter("/Photos")
+//	if err != nil { ... }
+//	defer it.Close()
+//	for it.Next() {
+//		fmt.Println(it.Entry().Path)
+//	}
+//	if err := it.Err(); err != nil { ... }
+type DirIter struct {
+	body  io.ReadCloser
+	dec   *json.Decoder
+	entry Metadata
+	err   error
+	done  bool
+
+	// Entries are decoded according to the JSONMode of the Session, and
+	// unknown fields are logged with the path of the call.
+	mode JSONMode
+	path string
+
+	// The other fields of the folder's metadata, kept for MetadataEach,
+	// and whether the contents array was found.
+	fields   map[string]json.RawMessage
+	contents bool
+
+	// The listing of a folder too large for the metadata call, built by
+	// ListLargeFolder.
+	entries []Metadata
+}
+
+// ReadDirIter starts listing the folder at path. Folders with more than
+// MaxFileLimit entries are listed with ListLargeFolder instead, and so are
+// held in memory.
+func (c *Client) ReadDirIter(path string) (*DirIter, error) {
+	params := c.makeParams(true)
+	params.Set("file_limit", strconv.Itoa(MaxFileLimit))
+	r, err := c.get(MetadataURL+c.filePath(path), params)
+	if err != nil {
+		return nil, err
+	}
+	if r.StatusCode == http.StatusNotAcceptable {
+		drainAndClose(r.Body)
+		meta, err := c.listFolderFromDelta(path)
+		if err != nil {
+			return nil, err
+		}
+		return &DirIter{entries: meta.Contents}, nil
+	}
+	if r.StatusCode != http.StatusOK {
+		defer drainAndClose(r.Body)
+		return nil, parseJSON(r, nil)
+	}
+
+	return c.newDirIter(r)
+}
+
+// newDirIter starts decoding the successful metadata response r.
+func (c *Client) newDirIter(r *http.Response) (*DirIter, error) {
+	it := &DirIter{
+		body:   r.Body,
+		dec:    json.NewDecoder(r.Body),
+		mode:   c.JSONMode,
+		path:   r.Request.URL.Path,
+		fields: make(map[string]json.RawMessage),
+	}
+	if err := it.findContents(); err != nil {
+		it.Close()
+		return nil, err
+	}
+	return it, nil
+}
+
+// findContents advances the decoder to the first entry of the contents array
+// of the response, keeping the fields before it.
+func (it *DirIter) findContents() error {
+	if err := it.expect(json.Delim('{')); err != nil {
+		return err
+	}
+	if err := it.readFields(); err != nil {
+		return err
+	}
+	// A file, which has no contents.
+	it.done = !it.contents
+	return nil
+}
+
+// readFields reads the fields of the folder's metadata up to its contents
+// array, which it enters, or to the end of the object.
+func (it *DirIter) readFields() error {
+	for it.dec.More() {
+		tok, err := it.dec.Token()
+		if err != nil {
+			return err
+		}
+		if tok == "contents" && !it.contents {
+			if tok, err = it.dec.Token(); err != nil {
+				return err
+			}
+			if tok == nil {
+				continue
+			}
+			if tok != json.Delim('[') {
+				return fmt.Errorf("expected %v in metadata response, got %v", json.Delim('['), tok)
+			}
+			it.contents = true
+			return nil
+		}
+		var raw json.RawMessage
+		if err := it.dec.Decode(&raw); err != nil {
+			return err
+		}
+		key, _ := tok.(string)
+		it.fields[key] = raw
+	}
+	return nil
+}
+
+func (it *DirIter) expect(delim json.Delim) error {
+	tok, err := it.dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok != delim {
+		return fmt.Errorf("expected %v in metadata response, got %v", delim, tok)
+	}
+	return nil
+}
+
+// Next advances to the next entry, and reports whether there is one.
+func (it *DirIter) Next() bool {
+	if it.done || it.err != nil {
+		return false
+	}
+	if it.dec == nil {
+		if len(it.entries) == 0 {
+			it.done = true
+			return false
+		}
+		it.entry, it.entries = it.entries[0], it.entries[1:]
+		return true
+	}
+	if !it.dec.More() {
+		it.done = true
+		return false
+	}
+	it.entry = Metadata{}
+	if it.mode == JSONLenient {
+		it.err = it.dec.Decode(&it.entry)
+		return it.err == nil
+	}
+	var raw json.RawMessage
+	if it.err = it.dec.Decode(&raw); it.err != nil {
+		return false
+	}
+	it.err = unmarshalMode(it.mode, it.path, raw, &it.entry)
+	return it.err == nil
+}
+
+// folder reads the rest of the response, and returns the metadata of the
+// folder without its contents. If the entries weren't all read, it returns
+// the fields which came before them.
+func (it *DirIter) folder() (*Metadata, error) {
+	if it.err == nil && it.done && it.dec != nil {
+		if it.contents {
+			if err := it.expect(json.Delim(']')); err != nil {
+				return nil, err
+			}
+			if err := it.readFields(); err != nil {
+				return nil, err
+			}
+		}
+		if err := it.expect(json.Delim('}')); err != nil {
+			return nil, err
+		}
+	}
+	data, err := json.Marshal(it.fields)
+	if err != nil {
+		return nil, err
+	}
+	var meta Metadata
+	if err := unmarshalMode(it.mode, it.path, data, &meta); err != nil {
+		return nil, err
+	}
+	return &meta, nil
+}
+
+// Entry returns the current entry. It is overwritten by the next call to Next.
+func (it *DirIter) Entry() *Metadata {
+	return &it.entry
+}
+
+// Err returns the error which stopped the iteration, if any.
+func (it *DirIter) Err() error {
+	return it.err
+}
+
+// Close releases the response. The rest of the listing is not read.
+func (it *DirIter) Close() error {
+	if it.body == nil {
+		return nil
+	}
+	err := it.body.Close()
+	it.body = nil
+	return err
+}
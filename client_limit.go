@@ -0,0 +1,104 @@
+package dropbox
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// A Limiter paces requests on the client side, so an application stays within
+// the rate limits of Dropbox instead of running into them.
+type Limiter interface {
+	// Wait blocks until a request may be sent, or ctx is done.
+	Wait(ctx context.Context) error
+}
+
+// A TokenBucket is a Limiter allowing Rate requests per second on average,
+// with bursts of up to Burst requests. A Burst below 1 allows no bursts, as if
+// it were 1. It is safe for concurrent use.
+type TokenBucket struct {
+	Rate  float64
+	Burst int
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket creates a full TokenBucket with the given settings.
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	tb := &TokenBucket{Rate: rate, Burst: burst, last: time.Now()}
+	tb.tokens = tb.burst()
+	return tb
+}
+
+// burst returns the number of tokens the bucket holds when full.
+func (tb *TokenBucket) burst() float64 {
+	if tb.Burst < 1 {
+		return 1
+	}
+	return float64(tb.Burst)
+}
+
+// Wait takes a token from the bucket, waiting for one to become available.
+func (tb *TokenBucket) Wait(ctx context.Context) error {
+	for {
+		wait := tb.take()
+		if wait <= 0 {
+			return nil
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// take removes a token if one is available, otherwise it returns how long
+// until there will be one.
+func (tb *TokenBucket) take() time.Duration {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	if tb.Rate <= 0 {
+		return 0
+	}
+	now := time.Now()
+	if !tb.last.IsZero() {
+		tb.tokens += now.Sub(tb.last).Seconds() * tb.Rate
+	}
+	tb.last = now
+	if burst := tb.burst(); tb.tokens > burst {
+		tb.tokens = burst
+	}
+	if tb.tokens >= 1 {
+		tb.tokens--
+		return 0
+	}
+	return time.Duration((1 - tb.tokens) / tb.Rate * float64(time.Second))
+}
+
+// limiter returns the Limiter for req, which is ContentLimiter for requests to
+// the content servers if it is set, and Limiter otherwise.
+func (c *Client) limiter(req *http.Request) Limiter {
+	if c.ContentLimiter != nil && c.isContentHost(req.URL.Host) {
+		return c.ContentLimiter
+	}
+	return c.Limiter
+}
+
+func (c *Client) isContentHost(host string) bool {
+	if host == ContentHost || host == ContentV2Host {
+		return true
+	}
+	if c.BaseURLs.Content != "" {
+		if u, err := url.Parse(c.BaseURLs.Content); err == nil && u.Host == host {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,36 @@
+package dropbox
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTokenBucketZeroBurst(t *testing.T) {
+	for _, tb := range []*TokenBucket{
+		NewTokenBucket(100, 0),
+		{Rate: 100},
+	} {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		for i := 0; i < 3; i++ {
+			if err := tb.Wait(ctx); err != nil {
+				t.Fatalf("Wait %d of bucket with Burst %d: %v", i, tb.Burst, err)
+			}
+		}
+		cancel()
+	}
+}
+
+func TestTokenBucketBurst(t *testing.T) {
+	tb := NewTokenBucket(1, 3)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	for i := 0; i < 3; i++ {
+		if err := tb.Wait(ctx); err != nil {
+			t.Fatalf("Wait %d within the burst: %v", i, err)
+		}
+	}
+	if err := tb.Wait(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Wait beyond the burst = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
@@ -0,0 +1,171 @@
+package dropbox
+
+import (
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+)
+
+// MaxFileLimit is the largest file_limit accepted by the metadata call.
+const MaxFileLimit = 25000
+
+// ListLargeFolder returns the metadata, including contents, of the folder at
+// the given path, no matter how many entries it has. The metadata call refuses
+// to list folders with more than MaxFileLimit entries, so for those the listing
+// is built from the delta call restricted to the folder's path instead.
+func (c *Client) ListLargeFolder(path string) (*Metadata, error) {
+	meta, _, err := c.Metadata(path, MaxFileLimit, "", true, false, "")
+	if apierr, ok := err.(*APIError); ok && apierr.Code == http.StatusNotAcceptable {
+		return c.listFolderFromDelta(path)
+	}
+	return meta, err
+}
+
+func (c *Client) listFolderFromDelta(dir string) (*Metadata, error) {
+	meta, _, err := c.Metadata(dir, 0, "", false, false, "")
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := NormalizePath(dir)
+	children := make(map[string]*Metadata)
+	cursor := ""
+	for {
+		delta, err := c.DeltaPrefix(cursor, prefix)
+		if err != nil {
+			return nil, err
+		}
+		if delta.Reset {
+			children = make(map[string]*Metadata)
+		}
+		for _, e := range delta.Entries {
+			if !EqualPath(path.Dir(e.Path), prefix) {
+				continue
+			}
+			if e.Meta == nil {
+				delete(children, e.Path)
+			} else {
+				children[e.Path] = e.Meta
+			}
+		}
+		cursor = delta.Cursor
+		if !delta.HasMore {
+			break
+		}
+	}
+
+	keys := make([]string, 0, len(children))
+	for k := range children {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	meta.Contents = make([]Metadata, len(keys))
+	for i, k := range keys {
+		meta.Contents[i] = *children[k]
+	}
+	return meta, nil
+}
+
+// MetadataEach lists the folder at path like Metadata, but passes its entries
+// to fn one at a time as they are decoded, instead of collecting them in the
+// Contents of the folder's metadata, which is returned without them. This keeps
+// the memory used by folders of tens of thousands of entries small. fileLimit
+// and deleted behave as in Metadata. If fn returns an error, the listing stops
+// and the error is returned, unless it is ErrStopWalk. The entry passed to fn
+// is reused, so it must not be retained. The response is decoded by a DirIter,
+// according to the JSONMode of the Session.
+func (c *Client) MetadataEach(path string, fileLimit int, deleted bool, fn WalkFunc) (*Metadata, error) {
+	params := c.makeParams(true)
+	if fileLimit > 0 {
+		params.Set("file_limit", strconv.FormatInt(int64(fileLimit), 10))
+	}
+	if deleted {
+		params.Set("include_deleted", "true")
+	}
+	r, err := c.get(MetadataURL+c.filePath(path), params)
+	if err != nil {
+		return nil, err
+	}
+	defer drainAndClose(r.Body)
+	if r.StatusCode != http.StatusOK {
+		return nil, parseJSON(r, nil)
+	}
+
+	it, err := c.newDirIter(r)
+	if err != nil {
+		return nil, err
+	}
+	for it.Next() {
+		if err := fn(it.Entry()); err != nil {
+			meta, _ := it.folder()
+			if err == ErrStopWalk {
+				return meta, nil
+			}
+			return meta, err
+		}
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	return it.folder()
+}
+
+// ListFolderOptions control a version 2 folder listing.
+type ListFolderOptions struct {
+	Recursive      bool // List the contents of all subfolders too
+	IncludeDeleted bool // Include entries for deleted files and folders
+	Limit          int  // Maximum entries per page, the server default if 0
+}
+
+// A FolderListing is one page of a version 2 folder listing. If HasMore is
+// true, the next page is retrieved by passing Cursor to ListFolderContinue.
+type FolderListing struct {
+	Entries []FileMetadata `json:"entries"`
+	Cursor  string         `json:"cursor"`
+	HasMore bool           `json:"has_more"`
+}
+
+// ListFolder lists the contents of the folder at path using the version 2 API,
+// which pages through folders of any size, and can list them recursively.
+func (c *Client) ListFolder(path string, opts *ListFolderOptions) (listing *FolderListing, err error) {
+	if opts == nil {
+		opts = &ListFolderOptions{}
+	}
+	arg := struct {
+		Path           string `json:"path"`
+		Recursive      bool   `json:"recursive"`
+		IncludeDeleted bool   `json:"include_deleted"`
+		Limit          int    `json:"limit,omitempty"`
+	}{v2Path(path), opts.Recursive, opts.IncludeDeleted, opts.Limit}
+	err = c.rpc("/files/list_folder", arg, &listing)
+	return
+}
+
+// ListFolderContinue returns the next page of a ListFolder listing. Once all
+// pages have been read, the cursor can be used to retrieve later changes.
+func (c *Client) ListFolderContinue(cursor string) (listing *FolderListing, err error) {
+	arg := struct {
+		Cursor string `json:"cursor"`
+	}{cursor}
+	err = c.rpc("/files/list_folder/continue", arg, &listing)
+	return
+}
+
+// ListFolderAll reads all pages of a ListFolder listing, returning all the
+// entries and the final cursor.
+func (c *Client) ListFolderAll(path string, opts *ListFolderOptions) ([]FileMetadata, string, error) {
+	listing, err := c.ListFolder(path, opts)
+	if err != nil {
+		return nil, "", err
+	}
+	entries := listing.Entries
+	for listing.HasMore {
+		listing, err = c.ListFolderContinue(listing.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		entries = append(entries, listing.Entries...)
+	}
+	return entries, listing.Cursor, nil
+}
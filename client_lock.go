@@ -0,0 +1,91 @@
+package dropbox
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// A FileLockInfo describes the lock on a file, as found in FileMetadata.
+type FileLockInfo struct {
+	IsLockholder        bool      `json:"is_lockholder"`
+	LockholderName      string    `json:"lockholder_name"`
+	LockholderAccountID string    `json:"lockholder_account_id"`
+	Created             time.Time `json:"created"`
+}
+
+// A FileLock describes a lock held on a file.
+type FileLock struct {
+	Created             time.Time `json:"created"`
+	LockHolderAccountID string    `json:"lock_holder_account_id"`
+	LockHolderTeamID    string    `json:"lock_holder_team_id"`
+}
+
+// A LockResult is the outcome of a lock operation on a single file. If the
+// operation failed for the file, Error describes why and the other fields are
+// nil.
+type LockResult struct {
+	Metadata *FileMetadata
+	Lock     *FileLock
+	Error    string
+}
+
+// LockFiles locks the files at the given paths, preventing other users from
+// editing them until they are unlocked.
+func (c *Client) LockFiles(paths ...string) ([]LockResult, error) {
+	return c.lockBatch("/files/lock_file_batch", paths)
+}
+
+// UnlockFiles releases the locks on the files at the given paths.
+func (c *Client) UnlockFiles(paths ...string) ([]LockResult, error) {
+	return c.lockBatch("/files/unlock_file_batch", paths)
+}
+
+// FileLocks returns the locks currently held on the files at the given paths.
+func (c *Client) FileLocks(paths ...string) ([]LockResult, error) {
+	return c.lockBatch("/files/get_file_lock_batch", paths)
+}
+
+func (c *Client) lockBatch(endpoint string, paths []string) ([]LockResult, error) {
+	type lockArg struct {
+		Path string `json:"path"`
+	}
+	arg := struct {
+		Entries []lockArg `json:"entries"`
+	}{make([]lockArg, len(paths))}
+	for i, p := range paths {
+		arg.Entries[i].Path = v2Path(p)
+	}
+
+	var res struct {
+		Entries []struct {
+			Tag      string          `json:".tag"`
+			Metadata *FileMetadata   `json:"metadata"`
+			Lock     json.RawMessage `json:"lock"`
+			Failure  struct {
+				Tag string `json:".tag"`
+			} `json:"failure"`
+		} `json:"entries"`
+	}
+	if err := c.rpc(endpoint, arg, &res); err != nil {
+		return nil, err
+	}
+
+	results := make([]LockResult, len(res.Entries))
+	for i, e := range res.Entries {
+		if e.Tag != "success" {
+			results[i].Error = e.Failure.Tag
+			continue
+		}
+		results[i].Metadata = e.Metadata
+		if len(e.Lock) > 0 {
+			var lock struct {
+				Content FileLock `json:"content"`
+			}
+			if err := json.Unmarshal(e.Lock, &lock); err != nil {
+				return nil, err
+			}
+			results[i].Lock = &lock.Content
+		}
+	}
+	return results, nil
+}
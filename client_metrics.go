@@ -0,0 +1,95 @@
+package dropbox
+
+import (
+	"expvar"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Metrics counts the calls, errors and bytes transferred of each API endpoint,
+// and publishes them with expvar, so they are served by the /debug/vars
+// handler. The counts of an endpoint, eg: "files" or "files/list_folder", are
+// the "calls", "errors", "bytes_sent" and "bytes_received" entries of a map
+// with its name. One Metrics may be shared by several sessions.
+type Metrics struct {
+	vars *expvar.Map
+	mu   sync.Mutex
+}
+
+// NewMetrics creates a Metrics published with expvar under name. Like
+// expvar.NewMap, it panics if the name is already in use.
+func NewMetrics(name string) *Metrics {
+	return &Metrics{vars: expvar.NewMap(name)}
+}
+
+// Map returns the map holding the counts of each endpoint.
+func (m *Metrics) Map() *expvar.Map {
+	return m.vars
+}
+
+// endpointName returns the name of the endpoint a request is for. Version 1
+// paths end with the path of a file after the root, which is left out.
+func endpointName(req *http.Request) string {
+	segments := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	for i, s := range segments {
+		switch s {
+		case "2":
+			return strings.Join(segments[i+1:], "/")
+		case "1":
+			name := segments[i+1:]
+			for j, n := range name {
+				if n == string(DropboxRoot) || n == string(SandboxRoot) || n == "auto" {
+					name = name[:j]
+					break
+				}
+			}
+			if len(name) > 2 {
+				name = name[:2]
+			}
+			return strings.Join(name, "/")
+		}
+	}
+	return req.URL.Path
+}
+
+func (m *Metrics) endpoint(name string) *expvar.Map {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if v, ok := m.vars.Get(name).(*expvar.Map); ok {
+		return v
+	}
+	v := new(expvar.Map).Init()
+	m.vars.Set(name, v)
+	return v
+}
+
+// record counts a call. The bytes of the response body are counted as they
+// are read.
+func (m *Metrics) record(req *http.Request, resp *http.Response, err error) {
+	v := m.endpoint(endpointName(req))
+	v.Add("calls", 1)
+	if req.ContentLength > 0 {
+		v.Add("bytes_sent", req.ContentLength)
+	}
+	if err != nil || resp.StatusCode >= http.StatusBadRequest {
+		v.Add("errors", 1)
+	}
+	if resp != nil {
+		resp.Body = &countingBody{resp.Body, v}
+	}
+}
+
+type countingBody struct {
+	io.ReadCloser
+	vars *expvar.Map
+}
+
+func (cb *countingBody) Read(p []byte) (int, error) {
+	n, err := cb.ReadCloser.Read(p)
+	if n > 0 {
+		cb.vars.Add("bytes_received", int64(n))
+	}
+	return n, err
+}
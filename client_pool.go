@@ -0,0 +1,142 @@
+package dropbox
+
+import (
+	"io"
+	"net/http"
+	"sync"
+)
+
+// DefaultPoolConns is the number of concurrent connections of a ClientPool
+// created with a limit of zero.
+const DefaultPoolConns = 32
+
+// A ClientPool shares one HTTP transport and the rate limiters among the
+// clients of many users of an app, eg: in a server acting for each of its
+// users. However many users there are, the clients of a pool have at most
+// MaxConns requests in flight together, further requests waiting for one to
+// complete, and idle connections are kept open for reuse by any of them.
+//
+// A request holds its slot until its response body is read to the end or
+// closed, so bodies must always be closed, and longpolls occupy a slot while
+// they wait. A ClientPool is safe for concurrent use, but its fields must not
+// be changed once clients have been created.
+type ClientPool struct {
+	AppKey, AppSecret string
+
+	// Limiter and ContentLimiter, if set, are the limiters shared by the
+	// sessions of the pool, as described by Session.
+	Limiter        Limiter
+	ContentLimiter Limiter
+
+	// Retry, if set, is the RetryPolicy of the sessions of the pool.
+	Retry *RetryPolicy
+
+	client *http.Client
+	slots  chan struct{}
+}
+
+// NewClientPool creates a ClientPool for the given app, allowing maxConns
+// concurrent requests, or DefaultPoolConns if maxConns is zero or less.
+func NewClientPool(appKey, appSecret string, maxConns int) *ClientPool {
+	if maxConns <= 0 {
+		maxConns = DefaultPoolConns
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = maxConns
+	p := &ClientPool{
+		AppKey:    appKey,
+		AppSecret: appSecret,
+		slots:     make(chan struct{}, maxConns),
+	}
+	p.client = &http.Client{
+		Transport:     &poolTransport{base: transport, slots: p.slots},
+		CheckRedirect: SafeRedirect,
+	}
+	return p
+}
+
+// MaxConns returns the number of requests the clients of the pool may have in
+// flight together.
+func (p *ClientPool) MaxConns() int {
+	return cap(p.slots)
+}
+
+// InUse returns the number of requests of the clients of the pool currently
+// in flight.
+func (p *ClientPool) InUse() int {
+	return len(p.slots)
+}
+
+// NewSession creates a Session of the pool's app authorized with the OAuth v1
+// accessToken, which sends its requests through the pool. Set its OAuth2Token
+// to use an OAuth2 token instead.
+func (p *ClientPool) NewSession(accessToken *Credentials) *Session {
+	s := NewSession(p.AppKey, p.AppSecret, p.client, accessToken)
+	s.Limiter = p.Limiter
+	s.ContentLimiter = p.ContentLimiter
+	s.Retry = p.Retry
+	return s
+}
+
+// NewClient creates a Client working on root for the user whose OAuth v1
+// access token is accessToken.
+func (p *ClientPool) NewClient(accessToken *Credentials, root AccessRoot) *Client {
+	return NewClient(p.NewSession(accessToken), root)
+}
+
+// NewOAuth2Client creates a Client working on root for the user whose OAuth2
+// bearer token is token.
+func (p *ClientPool) NewOAuth2Client(token string, root AccessRoot) *Client {
+	s := p.NewSession(nil)
+	s.OAuth2Token = token
+	return NewClient(s, root)
+}
+
+// A poolTransport sends requests with base once one of the slots is free.
+type poolTransport struct {
+	base  http.RoundTripper
+	slots chan struct{}
+}
+
+func (t *poolTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	select {
+	case t.slots <- struct{}{}:
+	case <-req.Context().Done():
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, req.Context().Err()
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		<-t.slots
+		return nil, err
+	}
+	resp.Body = &slotBody{ReadCloser: resp.Body, slots: t.slots}
+	return resp, nil
+}
+
+// A slotBody frees its slot once it is read to the end or closed.
+type slotBody struct {
+	io.ReadCloser
+	slots chan struct{}
+	once  sync.Once
+}
+
+func (b *slotBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.release()
+	}
+	return n, err
+}
+
+func (b *slotBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
+
+func (b *slotBody) release() {
+	b.once.Do(func() { <-b.slots })
+}
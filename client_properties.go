@@ -0,0 +1,132 @@
+package dropbox
+
+// A PropertyField is a single named value in a PropertyGroup.
+type PropertyField struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// A PropertyGroup is a set of custom properties attached to a file, following
+// the structure of a property template.
+type PropertyGroup struct {
+	TemplateID string          `json:"template_id"`
+	Fields     []PropertyField `json:"fields"`
+}
+
+// A PropertyFieldTemplate describes one field of a PropertyTemplate. The only
+// type currently supported by Dropbox is "string".
+type PropertyFieldTemplate struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Type        Tag    `json:"type"`
+}
+
+// A PropertyTemplate defines the structure of a group of custom properties.
+type PropertyTemplate struct {
+	Name        string                  `json:"name"`
+	Description string                  `json:"description"`
+	Fields      []PropertyFieldTemplate `json:"fields"`
+}
+
+// StringField returns a PropertyFieldTemplate for a string field.
+func StringField(name, description string) PropertyFieldTemplate {
+	return PropertyFieldTemplate{name, description, Tag{"string"}}
+}
+
+// A PropertySearchMatch is a file found by SearchProperties.
+type PropertySearchMatch struct {
+	ID             string          `json:"id"`
+	Path           string          `json:"path"`
+	IsDeleted      bool            `json:"is_deleted"`
+	PropertyGroups []PropertyGroup `json:"property_groups"`
+}
+
+// AddPropertyTemplate defines a new property template for the user, and
+// returns its id.
+func (c *Client) AddPropertyTemplate(t *PropertyTemplate) (string, error) {
+	var res struct {
+		TemplateID string `json:"template_id"`
+	}
+	err := c.rpc("/file_properties/templates/add_for_user", t, &res)
+	return res.TemplateID, err
+}
+
+// PropertyTemplate returns the property template with the given id.
+func (c *Client) PropertyTemplate(templateID string) (t *PropertyTemplate, err error) {
+	arg := struct {
+		TemplateID string `json:"template_id"`
+	}{templateID}
+	err = c.rpc("/file_properties/templates/get_for_user", arg, &t)
+	return
+}
+
+// PropertyTemplates returns the ids of all property templates of the user.
+func (c *Client) PropertyTemplates() ([]string, error) {
+	var res struct {
+		TemplateIDs []string `json:"template_ids"`
+	}
+	err := c.rpc("/file_properties/templates/list_for_user", nil, &res)
+	return res.TemplateIDs, err
+}
+
+// UpdatePropertyTemplate changes the name and description of the property
+// template with the given id (if not the empty string), and adds the given
+// fields to it.
+func (c *Client) UpdatePropertyTemplate(templateID, name, description string, addFields []PropertyFieldTemplate) error {
+	arg := struct {
+		TemplateID  string                  `json:"template_id"`
+		Name        string                  `json:"name,omitempty"`
+		Description string                  `json:"description,omitempty"`
+		AddFields   []PropertyFieldTemplate `json:"add_fields,omitempty"`
+	}{templateID, name, description, addFields}
+	return c.rpc("/file_properties/templates/update_for_user", arg, nil)
+}
+
+type propertiesArg struct {
+	Path           string          `json:"path"`
+	PropertyGroups []PropertyGroup `json:"property_groups"`
+}
+
+// AddProperties attaches the given property groups to the file at path. Each
+// template may only be used once per file.
+func (c *Client) AddProperties(path string, groups ...PropertyGroup) error {
+	return c.rpc("/file_properties/properties/add", propertiesArg{v2Path(path), groups}, nil)
+}
+
+// OverwriteProperties replaces the given property groups of the file at path.
+func (c *Client) OverwriteProperties(path string, groups ...PropertyGroup) error {
+	return c.rpc("/file_properties/properties/overwrite", propertiesArg{v2Path(path), groups}, nil)
+}
+
+// RemoveProperties removes the property groups with the given template ids
+// from the file at path.
+func (c *Client) RemoveProperties(path string, templateIDs ...string) error {
+	arg := struct {
+		Path        string   `json:"path"`
+		TemplateIDs []string `json:"property_template_ids"`
+	}{v2Path(path), templateIDs}
+	return c.rpc("/file_properties/properties/remove", arg, nil)
+}
+
+// SearchProperties returns the files that have a value matching query in a
+// property field with the given name.
+func (c *Client) SearchProperties(fieldName, query string) ([]PropertySearchMatch, error) {
+	type mode struct {
+		Tag       string `json:".tag"`
+		FieldName string `json:"field_name"`
+	}
+	type searchQuery struct {
+		Query           string `json:"query"`
+		Mode            mode   `json:"mode"`
+		LogicalOperator Tag    `json:"logical_operator"`
+	}
+
+	arg := struct {
+		Queries []searchQuery `json:"queries"`
+	}{[]searchQuery{{query, mode{"field_name", fieldName}, Tag{"or_operator"}}}}
+	var res struct {
+		Matches []PropertySearchMatch `json:"matches"`
+	}
+	err := c.rpc("/file_properties/properties/search", arg, &res)
+	return res.Matches, err
+}
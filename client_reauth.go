@@ -0,0 +1,113 @@
+package dropbox
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// A ReauthorizeFunc is called when a call of a Client is refused with an
+// AuthorizationError, eg: because the user revoked the app's access or the
+// token expired. It should update the credentials of s, its AccessToken or
+// OAuth2Token, for instance by refreshing them or loading them again from its
+// TokenStore. If it succeeds the call is sent once more with the new
+// credentials, otherwise the call fails with an AuthorizationError wrapping
+// its error.
+//
+// Calls failing concurrently each call the function, so it must be safe for
+// concurrent use, and should avoid acquiring new tokens when another call
+// already did.
+type ReauthorizeFunc func(s *Session) error
+
+// WithReauthorize returns a copy of the client which calls fn when its calls
+// are refused, instead of the Session's Reauthorize. A nil fn disables
+// reauthorization.
+func (c *Client) WithReauthorize(fn ReauthorizeFunc) *Client {
+	clone := *c
+	if fn == nil {
+		fn = func(*Session) error { return errNoReauthorize }
+	}
+	clone.reauth = fn
+	return &clone
+}
+
+// errNoReauthorize is returned by the ReauthorizeFunc of clients which had it
+// disabled with WithReauthorize.
+var errNoReauthorize = errors.New("reauthorization disabled")
+
+func (c *Client) reauthorizeFunc() ReauthorizeFunc {
+	if c.reauth != nil {
+		return c.reauth
+	}
+	return c.Reauthorize
+}
+
+// reauthorize handles req having failed with the AuthorizationError err. If
+// the client has a ReauthorizeFunc and req can be sent again, the credentials
+// are renewed and req is signed with them and sent once more.
+func (c *Client) reauthorize(req *http.Request, err error) (*http.Response, error) {
+	fn := c.reauthorizeFunc()
+	if fn == nil || !rewind(req) {
+		return nil, err
+	}
+	if rerr := fn(c.Session); rerr != nil {
+		if rerr == errNoReauthorize {
+			return nil, err
+		}
+		return nil, &AuthorizationError{"reauthorize", rerr}
+	}
+	if err := c.resign(req); err != nil {
+		return nil, err
+	}
+	return c.send(req)
+}
+
+// resign replaces the credentials of req, built by newRequest, with the
+// current ones of the Session.
+func (c *Client) resign(req *http.Request) error {
+	form := req.Header.Get("Content-Type") == "application/x-www-form-urlencoded"
+	params := req.URL.Query()
+	if form {
+		body, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return err
+		}
+		if params, err = url.ParseQuery(string(body)); err != nil {
+			return err
+		}
+	}
+	// Requests signed in the header, including all version 2 calls, stay so.
+	querySigned := req.Header.Get("Authorization") == ""
+	for k := range params {
+		if strings.HasPrefix(k, "oauth_") {
+			delete(params, k)
+		}
+	}
+
+	req.Header.Del("Authorization")
+	u := *req.URL
+	u.RawQuery = ""
+	if querySigned && c.OAuth2Token == "" {
+		if err := c.signParam(req.Method, u.String(), params); err != nil {
+			return err
+		}
+	} else if err := c.authorize(req, params); err != nil {
+		return err
+	}
+
+	if !form {
+		req.URL.RawQuery = params.Encode()
+		return nil
+	}
+	body := params.Encode()
+	req.ContentLength = int64(len(body))
+	req.Body = ioutil.NopCloser(strings.NewReader(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(strings.NewReader(body)), nil
+	}
+	return nil
+}
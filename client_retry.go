@@ -0,0 +1,242 @@
+package dropbox
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A RetryPolicy controls how requests which fail with a network error or a
+// server error (429 and 5xx statuses) are retried. Retries wait with
+// exponential backoff, starting at InitialBackoff and doubling up to
+// MaxBackoff, unless the server asks for a specific delay with Retry-After.
+//
+// The budget of a call is MaxAttempts attempts in total, and no retry is
+// started if it would end after MaxElapsed since the first attempt, so a stuck
+// endpoint can't hold a caller for long. A zero MaxElapsed means no limit.
+//
+// Only calls which are safe to repeat are retried: GET requests other than
+// restore, and the calls which just read data, like delta or list_folder.
+// Calls that change the dropbox may have taken effect before failing, so they
+// aren't repeated, with the exception of uploads which replace a given parent
+// revision if RetryUploads is set: repeating those can't create duplicates, as
+// the server refuses an upload whose parent revision is no longer current.
+// Requests whose body can't be replayed are never retried.
+type RetryPolicy struct {
+	MaxAttempts    int
+	MaxElapsed     time.Duration
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	RetryUploads   bool
+}
+
+// DefaultRetryPolicy is a reasonable policy for sessions which want retries.
+var DefaultRetryPolicy = &RetryPolicy{
+	MaxAttempts:    4,
+	MaxElapsed:     time.Minute,
+	InitialBackoff: 500 * time.Millisecond,
+	MaxBackoff:     10 * time.Second,
+}
+
+// noRetry is used by sessions without a RetryPolicy.
+var noRetry = &RetryPolicy{MaxAttempts: 1}
+
+// WithRetry returns a copy of the client which retries its calls according to
+// rp instead of the Session's RetryPolicy. A nil rp disables retries.
+func (c *Client) WithRetry(rp *RetryPolicy) *Client {
+	clone := *c
+	if rp == nil {
+		rp = noRetry
+	}
+	clone.retry = rp
+	return &clone
+}
+
+func (c *Client) retryPolicy() *RetryPolicy {
+	if c.retry != nil {
+		return c.retry
+	}
+	if c.Retry != nil {
+		return c.Retry
+	}
+	return noRetry
+}
+
+// do sends req with client, retrying it as allowed by the policy. The last
+// response or error is returned. observe, if not nil, is told the outcome and
+// duration of every attempt, including those which are retried.
+func (rp *RetryPolicy) do(client *http.Client, req *http.Request, observe func(*http.Response, error, time.Duration)) (*http.Response, error) {
+	start := time.Now()
+	backoff := rp.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		sent := time.Now()
+		resp, err := client.Do(req)
+		if observe != nil {
+			observe(resp, err, time.Since(sent))
+		}
+		if attempt >= rp.MaxAttempts || !shouldRetry(resp, err) || !rp.canRetry(req) || !rewind(req) {
+			return resp, err
+		}
+
+		wait := backoff
+		if d, ok := retryAfter(resp); ok {
+			wait = d
+		}
+		if rp.MaxElapsed > 0 && time.Since(start)+wait > rp.MaxElapsed {
+			return resp, err
+		}
+		if resp != nil {
+			drainAndClose(resp.Body)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		}
+		if backoff *= 2; rp.MaxBackoff > 0 && backoff > rp.MaxBackoff {
+			backoff = rp.MaxBackoff
+		}
+	}
+}
+
+// shouldRetry reports whether an attempt failed in a way worth retrying.
+func shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError,
+		http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// safePaths are the endpoints, besides GET requests, which only read data and
+// so are safe to repeat.
+var safePaths = []string{
+	"/1/delta",
+	"/1/team/get_info",
+	"/1/team/members/list",
+	"/1/team/members/get_info",
+	"/2/files/get_metadata",
+	"/2/files/list_folder",
+	"/2/files/list_folder/continue",
+	"/2/files/list_revisions",
+	"/2/files/search_v2",
+	"/2/files/search/continue_v2",
+	"/2/files/get_temporary_link",
+	"/2/files/download",
+	"/2/files/tags/get",
+	"/2/files/get_file_lock_batch",
+	"/2/files/copy_batch/check_v2",
+	"/2/files/move_batch/check_v2",
+	"/2/files/delete_batch/check",
+	"/2/files/upload_session/finish_batch/check",
+	"/2/file_properties/templates/get_for_user",
+	"/2/file_properties/templates/list_for_user",
+	"/2/file_properties/properties/search",
+	"/2/sharing/list_file_members",
+	"/2/sharing/list_folder_members",
+	"/2/users/get_current_account",
+	"/2/users/get_space_usage",
+}
+
+// canRetry reports whether the policy allows req to be sent again.
+func (rp *RetryPolicy) canRetry(req *http.Request) bool {
+	return readOnly(req) || rp.RetryUploads && conditionalUpload(req)
+}
+
+// writingGets are the GET endpoints which change the dropbox, so are neither
+// safe to repeat nor read only, followed by the path of a file.
+var writingGets = []string{
+	"/1/restore/",
+}
+
+// readOnly reports whether req only reads data.
+func readOnly(req *http.Request) bool {
+	if req.Method == "GET" || req.Method == "HEAD" {
+		for _, p := range writingGets {
+			if strings.Contains(req.URL.Path, p) {
+				return false
+			}
+		}
+		return true
+	}
+	// Match suffixes, since BaseURLs may add a prefix to the path.
+	for _, p := range safePaths {
+		if strings.HasSuffix(req.URL.Path, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// conditionalUpload reports whether req is an upload which only succeeds if
+// the file is still at a given revision.
+func conditionalUpload(req *http.Request) bool {
+	p := req.URL.Path
+	switch {
+	case strings.Contains(p, "/1/files_put/"):
+		return req.URL.Query().Get("parent_rev") != ""
+	case strings.Contains(p, "/1/commit_chunked_upload/"):
+		if req.GetBody == nil {
+			return false
+		}
+		body, err := req.GetBody()
+		if err != nil {
+			return false
+		}
+		defer body.Close()
+		data, err := ioutil.ReadAll(body)
+		if err != nil {
+			return false
+		}
+		form, err := url.ParseQuery(string(data))
+		return err == nil && form.Get("parent_rev") != ""
+	case strings.HasSuffix(p, "/2/files/upload"), strings.HasSuffix(p, "/2/files/upload_session/finish"):
+		return strings.Contains(req.Header.Get(APIArgHeader), `"update"`)
+	}
+	return false
+}
+
+// rewind prepares the body of req to be sent again, and reports whether that
+// was possible.
+func rewind(req *http.Request) bool {
+	if req.Body == nil || req.Body == http.NoBody {
+		return true
+	}
+	if req.GetBody == nil {
+		return false
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return false
+	}
+	req.Body = body
+	return true
+}
+
+// retryAfter returns the delay requested by the Retry-After header of resp.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t), true
+	}
+	return 0, false
+}
@@ -0,0 +1,27 @@
+package dropbox
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRetryPolicyCanRetry(t *testing.T) {
+	tests := []struct {
+		method, url string
+		want        bool
+	}{
+		{"GET", MetadataURL + "/dropbox/a", true},
+		{"GET", "http://localhost:8080/prefix/1/metadata/dropbox/a", true},
+		{"GET", RestoreURL + "/dropbox/a?rev=1", false},
+		{"GET", "http://localhost:8080/1/restore/dropbox/a?rev=1", false},
+		{"POST", DeltaURL, true},
+		{"POST", APIv2Prefix + "/files/list_folder", true},
+		{"POST", APIv2Prefix + "/files/delete_v2", false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.url, nil)
+		if got := DefaultRetryPolicy.canRetry(req); got != tt.want {
+			t.Errorf("canRetry(%s %s) = %v, want %v", tt.method, tt.url, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package dropbox
+
+// SearchOptions control a version 2 search. The zero value searches file names
+// and contents everywhere in the dropbox.
+type SearchOptions struct {
+	Path           string   // Folder to search in, the entire dropbox if empty
+	MaxResults     int      // Maximum results per page, the server default if 0
+	FilenameOnly   bool     // Match file names only, not contents
+	Deleted        bool     // Search deleted files rather than active ones
+	FileExtensions []string // Restrict to these extensions, eg: "jpg"
+	FileCategories []string // Restrict to these categories, eg: "image", "pdf"
+	Highlights     bool     // Return HighlightSpans for each match
+}
+
+// A HighlightSpan is a piece of a matched file name, which is highlighted if it
+// matched the query.
+type HighlightSpan struct {
+	Text          string `json:"highlight_str"`
+	IsHighlighted bool   `json:"is_highlighted"`
+}
+
+// A SearchMatch is a single result of a version 2 search.
+type SearchMatch struct {
+	Metadata       *FileMetadata
+	MatchType      string // "filename", "file_content" or "filename_and_content"
+	HighlightSpans []HighlightSpan
+}
+
+// A SearchResult is one page of results of a version 2 search. If HasMore is
+// true, the next page is retrieved by passing Cursor to SearchContinue.
+type SearchResult struct {
+	Matches []SearchMatch
+	HasMore bool
+	Cursor  string
+}
+
+type searchResultJSON struct {
+	Matches []struct {
+		Metadata struct {
+			Metadata *FileMetadata `json:"metadata"`
+		} `json:"metadata"`
+		MatchType      Tag             `json:"match_type"`
+		HighlightSpans []HighlightSpan `json:"highlight_spans"`
+	} `json:"matches"`
+	HasMore bool   `json:"has_more"`
+	Cursor  string `json:"cursor"`
+}
+
+func (r *searchResultJSON) result() *SearchResult {
+	res := &SearchResult{
+		Matches: make([]SearchMatch, len(r.Matches)),
+		HasMore: r.HasMore,
+		Cursor:  r.Cursor,
+	}
+	for i, m := range r.Matches {
+		res.Matches[i] = SearchMatch{m.Metadata.Metadata, m.MatchType.Tag, m.HighlightSpans}
+	}
+	return res
+}
+
+// SearchV2 searches for files and folders matching query using the version 2
+// search API, which unlike Search supports paging through any number of
+// results.
+func (c *Client) SearchV2(query string, opts *SearchOptions) (*SearchResult, error) {
+	if opts == nil {
+		opts = &SearchOptions{}
+	}
+
+	type options struct {
+		Path           string   `json:"path,omitempty"`
+		MaxResults     int      `json:"max_results,omitempty"`
+		FileStatus     Tag      `json:"file_status"`
+		FilenameOnly   bool     `json:"filename_only"`
+		FileExtensions []string `json:"file_extensions,omitempty"`
+		FileCategories []Tag    `json:"file_categories,omitempty"`
+	}
+	arg := struct {
+		Query        string  `json:"query"`
+		Options      options `json:"options"`
+		MatchOptions struct {
+			IncludeHighlights bool `json:"include_highlights"`
+		} `json:"match_field_options"`
+	}{
+		Query: query,
+		Options: options{
+			Path:           v2Path(opts.Path),
+			MaxResults:     opts.MaxResults,
+			FileStatus:     Tag{"active"},
+			FilenameOnly:   opts.FilenameOnly,
+			FileExtensions: opts.FileExtensions,
+		},
+	}
+	if opts.Deleted {
+		arg.Options.FileStatus.Tag = "deleted"
+	}
+	for _, cat := range opts.FileCategories {
+		arg.Options.FileCategories = append(arg.Options.FileCategories, Tag{cat})
+	}
+	arg.MatchOptions.IncludeHighlights = opts.Highlights
+
+	var res searchResultJSON
+	if err := c.rpc("/files/search_v2", arg, &res); err != nil {
+		return nil, err
+	}
+	return res.result(), nil
+}
+
+// SearchContinue returns the next page of results of a SearchV2 call.
+func (c *Client) SearchContinue(cursor string) (*SearchResult, error) {
+	arg := struct {
+		Cursor string `json:"cursor"`
+	}{cursor}
+	var res searchResultJSON
+	if err := c.rpc("/files/search/continue_v2", arg, &res); err != nil {
+		return nil, err
+	}
+	return res.result(), nil
+}
@@ -0,0 +1,128 @@
+package dropbox
+
+import (
+	"strings"
+)
+
+// Access levels of members of shared files and folders.
+const (
+	AccessOwner  = "owner"
+	AccessEditor = "editor"
+	AccessViewer = "viewer"
+)
+
+// memberSelector identifies a member by email address or Dropbox id.
+type memberSelector struct {
+	Tag       string `json:".tag"`
+	Email     string `json:"email,omitempty"`
+	DropboxID string `json:"dropbox_id,omitempty"`
+}
+
+// selectMember accepts either an email address or a Dropbox account id.
+func selectMember(member string) memberSelector {
+	if strings.Contains(member, "@") {
+		return memberSelector{Tag: "email", Email: member}
+	}
+	return memberSelector{Tag: "dropbox_id", DropboxID: member}
+}
+
+// A SharedUser is a member of a shared file or folder with a Dropbox account.
+type SharedUser struct {
+	AccessType Tag `json:"access_type"`
+	User       struct {
+		AccountID   string `json:"account_id"`
+		Email       string `json:"email"`
+		DisplayName string `json:"display_name"`
+	} `json:"user"`
+	IsInherited bool `json:"is_inherited"`
+}
+
+// A SharedInvitee is an invited member of a shared file or folder who has not
+// yet joined.
+type SharedInvitee struct {
+	AccessType Tag `json:"access_type"`
+	Invitee    struct {
+		Email string `json:"email"`
+	} `json:"invitee"`
+}
+
+// SharedMembers lists the members of a shared file or folder.
+type SharedMembers struct {
+	Users    []SharedUser    `json:"users"`
+	Invitees []SharedInvitee `json:"invitees"`
+	Cursor   string          `json:"cursor"`
+}
+
+// AddFileMembers shares the file at path with the given members (email
+// addresses or Dropbox account ids) at the given access level (AccessViewer
+// or AccessEditor). If message is not empty it is included in the invitation.
+func (c *Client) AddFileMembers(path string, members []string, access, message string) error {
+	arg := struct {
+		File          string           `json:"file"`
+		Members       []memberSelector `json:"members"`
+		CustomMessage string           `json:"custom_message,omitempty"`
+		AccessLevel   Tag              `json:"access_level"`
+	}{File: v2Path(path), CustomMessage: message, AccessLevel: Tag{access}}
+	for _, m := range members {
+		arg.Members = append(arg.Members, selectMember(m))
+	}
+	return c.rpc("/sharing/add_file_member", arg, nil)
+}
+
+// RemoveFileMember stops sharing the file at path with the given member.
+func (c *Client) RemoveFileMember(path, member string) error {
+	arg := struct {
+		File   string         `json:"file"`
+		Member memberSelector `json:"member"`
+	}{v2Path(path), selectMember(member)}
+	return c.rpc("/sharing/remove_file_member_2", arg, nil)
+}
+
+// FileMembers lists the members of the shared file at path.
+func (c *Client) FileMembers(path string) (members *SharedMembers, err error) {
+	arg := struct {
+		File string `json:"file"`
+	}{v2Path(path)}
+	err = c.rpc("/sharing/list_file_members", arg, &members)
+	return
+}
+
+// AddFolderMembers shares the shared folder with the given id with the given
+// members (email addresses or Dropbox account ids) at the given access level.
+// If message is not empty it is included in the invitation.
+func (c *Client) AddFolderMembers(sharedFolderID string, members []string, access, message string) error {
+	type addMember struct {
+		Member      memberSelector `json:"member"`
+		AccessLevel Tag            `json:"access_level"`
+	}
+	arg := struct {
+		SharedFolderID string      `json:"shared_folder_id"`
+		Members        []addMember `json:"members"`
+		CustomMessage  string      `json:"custom_message,omitempty"`
+	}{SharedFolderID: sharedFolderID, CustomMessage: message}
+	for _, m := range members {
+		arg.Members = append(arg.Members, addMember{selectMember(m), Tag{access}})
+	}
+	return c.rpc("/sharing/add_folder_member", arg, nil)
+}
+
+// RemoveFolderMember removes the given member from the shared folder with the
+// given id. If leaveCopy is true the member keeps a copy of the folder. The
+// removal completes asynchronously.
+func (c *Client) RemoveFolderMember(sharedFolderID, member string, leaveCopy bool) error {
+	arg := struct {
+		SharedFolderID string         `json:"shared_folder_id"`
+		Member         memberSelector `json:"member"`
+		LeaveACopy     bool           `json:"leave_a_copy"`
+	}{sharedFolderID, selectMember(member), leaveCopy}
+	return c.rpc("/sharing/remove_folder_member", arg, nil)
+}
+
+// FolderMembers lists the members of the shared folder with the given id.
+func (c *Client) FolderMembers(sharedFolderID string) (members *SharedMembers, err error) {
+	arg := struct {
+		SharedFolderID string `json:"shared_folder_id"`
+	}{sharedFolderID}
+	err = c.rpc("/sharing/list_folder_members", arg, &members)
+	return
+}
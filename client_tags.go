@@ -0,0 +1,52 @@
+package dropbox
+
+type tagArg struct {
+	Path    string `json:"path"`
+	TagText string `json:"tag_text"`
+}
+
+// AddTag adds the tag to the file or folder at path. Tags may only contain
+// letters, numbers and underscores.
+func (c *Client) AddTag(path, tag string) error {
+	return c.rpc("/files/tags/add", tagArg{v2Path(path), tag}, nil)
+}
+
+// RemoveTag removes the tag from the file or folder at path.
+func (c *Client) RemoveTag(path, tag string) error {
+	return c.rpc("/files/tags/remove", tagArg{v2Path(path), tag}, nil)
+}
+
+// Tags returns the tags of the files and folders at the given paths, keyed by
+// path as given.
+func (c *Client) Tags(paths ...string) (map[string][]string, error) {
+	arg := struct {
+		Paths []string `json:"paths"`
+	}{make([]string, len(paths))}
+	for i, p := range paths {
+		arg.Paths[i] = v2Path(p)
+	}
+
+	var res struct {
+		PathsToTags []struct {
+			Tags []struct {
+				TagText string `json:"tag_text"`
+			} `json:"tags"`
+		} `json:"paths_to_tags"`
+	}
+	if err := c.rpc("/files/tags/get", arg, &res); err != nil {
+		return nil, err
+	}
+
+	tags := make(map[string][]string, len(paths))
+	for i, pt := range res.PathsToTags {
+		if i >= len(paths) {
+			break
+		}
+		list := make([]string, len(pt.Tags))
+		for j, t := range pt.Tags {
+			list[j] = t.TagText
+		}
+		tags[paths[i]] = list
+	}
+	return tags, nil
+}
@@ -0,0 +1,46 @@
+package dropbox
+
+import (
+	"time"
+)
+
+// A TemporaryLink is a direct download URL for a file, valid for four hours,
+// along with the file's metadata.
+type TemporaryLink struct {
+	Metadata *FileMetadata `json:"metadata"`
+	Link     string        `json:"link"`
+}
+
+// TemporaryLink returns a link which can be used to download the file at path
+// directly from Dropbox, without authentication, for the next four hours.
+func (c *Client) TemporaryLink(path string) (link *TemporaryLink, err error) {
+	arg := struct {
+		Path string `json:"path"`
+	}{v2Path(path)}
+	err = c.rpc("/files/get_temporary_link", arg, &link)
+	return
+}
+
+// TemporaryUploadLink returns a link which can be used to upload a file to
+// path directly to Dropbox, without authentication, with a single POST of the
+// file's contents (Content-Type: application/octet-stream). The link is valid
+// for the given duration, between 1 minute and 4 hours, or 4 hours if zero.
+// The opts control how the file is committed and may be nil, but ClientMTime
+// and ParentRev are ignored.
+func (c *Client) TemporaryUploadLink(path string, valid time.Duration, opts *UploadOptions) (string, error) {
+	var o UploadOptions
+	if opts != nil {
+		o = *opts
+		o.ClientMTime, o.ParentRev = time.Time{}, ""
+	}
+	arg := struct {
+		CommitInfo commitInfo `json:"commit_info"`
+		Duration   float64    `json:"duration,omitempty"`
+	}{newCommitInfo(path, &o), valid.Seconds()}
+
+	var res struct {
+		Link string `json:"link"`
+	}
+	err := c.rpc("/files/get_temporary_upload_link", arg, &res)
+	return res.Link, err
+}
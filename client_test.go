@@ -0,0 +1,37 @@
+package dropbox
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestUploadOptionsParams(t *testing.T) {
+	mtime := time.Date(2015, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name string
+		opts *UploadOptions
+		want url.Values
+	}{
+		{"nil", nil, url.Values{}},
+		{"zero", &UploadOptions{}, url.Values{"overwrite": {"false"}, "autorename": {"false"}}},
+		{"nil with mtime", (*UploadOptions)(nil).withMTime(mtime), url.Values{
+			"client_mtime": {"Fri, 02 Jan 2015 03:04:05 +0000"},
+		}},
+		{"zero with mtime", (&UploadOptions{}).withMTime(mtime), url.Values{
+			"overwrite": {"false"}, "autorename": {"false"},
+			"client_mtime": {"Fri, 02 Jan 2015 03:04:05 +0000"},
+		}},
+		{"mtime kept", (&UploadOptions{Overwrite: true, ParentRev: "a1", ClientMTime: mtime.Add(time.Hour)}).withMTime(mtime), url.Values{
+			"overwrite": {"true"}, "autorename": {"false"}, "parent_rev": {"a1"},
+			"client_mtime": {"Fri, 02 Jan 2015 04:04:05 +0000"},
+		}},
+	}
+	for _, tt := range tests {
+		params := url.Values{}
+		tt.opts.setParams(params)
+		if params.Encode() != tt.want.Encode() {
+			t.Errorf("%s: params = %s, want %s", tt.name, params.Encode(), tt.want.Encode())
+		}
+	}
+}
@@ -0,0 +1,274 @@
+package dropbox
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+)
+
+// Buffer sizes used by the transfer helpers.
+const (
+	CopyBufferSize   = 32 * 1024       // Size of buffers used to copy downloads
+	DefaultChunkSize = 4 * 1024 * 1024 // Default size of a chunked upload chunk
+)
+
+// Pools of reusable buffers, so applications performing many transfers don't
+// allocate (and collect) a fresh buffer for every file. The pools hold
+// pointers to slices so that returning a buffer doesn't itself allocate.
+var (
+	copyBufferPool = sync.Pool{
+		New: func() interface{} {
+			b := make([]byte, CopyBufferSize)
+			return &b
+		},
+	}
+	chunkBufferPool = sync.Pool{
+		New: func() interface{} {
+			b := make([]byte, DefaultChunkSize)
+			return &b
+		},
+	}
+)
+
+func getCopyBuffer() *[]byte  { return copyBufferPool.Get().(*[]byte) }
+func putCopyBuffer(b *[]byte) { copyBufferPool.Put(b) }
+
+// getChunkBuffer returns a buffer of at least size bytes, using the pool
+// when the request fits in a default sized chunk.
+func getChunkBuffer(size int) *[]byte {
+	if size > DefaultChunkSize {
+		b := make([]byte, size)
+		return &b
+	}
+	return chunkBufferPool.Get().(*[]byte)
+}
+
+func putChunkBuffer(b *[]byte) {
+	if cap(*b) == DefaultChunkSize {
+		chunkBufferPool.Put(b)
+	}
+}
+
+// copyBuffered copies from src to dst like io.Copy, but uses a pooled buffer.
+func copyBuffered(dst io.Writer, src io.Reader) (int64, error) {
+	buf := getCopyBuffer()
+	defer putCopyBuffer(buf)
+	return io.CopyBuffer(dst, src, *buf)
+}
+
+// Download writes the contents of the file at the given path (and revision if
+// rev is not the empty string) to w, returning the number of bytes written and
+// the file's metadata.
+func (c *Client) Download(path, rev string, w io.Writer) (int64, *Metadata, error) {
+	c, cancel := c.beginTransfer()
+	defer cancel()
+	body, meta, err := c.GetFile(path, rev)
+	if err != nil {
+		return 0, nil, c.transferError(path, 0, -1, err)
+	}
+	defer drainAndClose(body)
+
+	n, err := copyBuffered(w, body)
+	return n, meta, c.transferError(path, n, metaSize(meta), err)
+}
+
+// GetFileRange downloads length bytes of the file at path (and revision if rev
+// is not the empty string) starting at offset. If length <= 0 the rest of the
+// file is downloaded.
+func (c *Client) GetFileRange(path, rev string, offset, length int64) (io.ReadCloser, *Metadata, error) {
+	params := c.makeParams(false)
+	if rev != "" {
+		params.Set("rev", rev)
+	}
+	req, err := c.newRequest("GET", FilesURL+c.filePath(path), params, nil, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	if length > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	} else {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	response, err := c.doRequest(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	return fileResponse(response)
+}
+
+// FetchURL downloads the contents of a URL produced by Media or Shares. No
+// credentials are sent, and redirects are followed according to the Session's
+// redirect policy.
+func (c *Client) FetchURL(urlStr string) (io.ReadCloser, error) {
+	response, err := c.client().Get(urlStr)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		drainAndClose(response.Body)
+		return nil, fmt.Errorf("fetching %s: %s", urlStr, response.Status)
+	}
+	return response.Body, nil
+}
+
+// ChunkedPutFile uploads all the data from the given io.Reader to path using
+// the chunked upload API, sending chunkSize bytes at a time. If chunkSize <= 0
+// the chunk size is chosen by the Session's ChunkTuner, or is DefaultChunkSize
+// if there is none. The length of the data need not be known in advance.
+// The overwrite and parentRev arguments behave as in PutFile.
+func (c *Client) ChunkedPutFile(path string, overwrite bool, parentRev string, data io.Reader, chunkSize int) (*Metadata, error) {
+	opts := &UploadOptions{Overwrite: overwrite, ParentRev: parentRev, Autorename: true}
+	return c.upload(path, data, chunkSize, opts, false)
+}
+
+// Upload uploads all the data from the given io.Reader, whose length need not
+// be known, to path as controlled by opts, which may be nil. Data that fits in
+// a single chunk of DefaultChunkSize bytes is sent with one files_put request,
+// anything larger is buffered into chunks and sent with chunked_upload.
+func (c *Client) Upload(path string, data io.Reader, opts *UploadOptions) (*Metadata, error) {
+	return c.upload(path, data, 0, opts, true)
+}
+
+// upload sends data in chunks of chunkSize bytes, or of the sizes chosen by
+// nextChunkSize if chunkSize <= 0. If allowPut is true and the data fits in
+// the first chunk, which is at least DefaultChunkSize bytes, it is sent with
+// PutFileWithOptions instead.
+func (c *Client) upload(path string, data io.Reader, chunkSize int, opts *UploadOptions, allowPut bool) (*Metadata, error) {
+	c, cancel := c.beginTransfer()
+	defer cancel()
+	tuned := chunkSize <= 0
+	var state ChunkedUpload
+	var done func(*Metadata, int64, error)
+	for {
+		size := c.nextChunkSize(chunkSize)
+		if allowPut && state.UploadId == "" && size < DefaultChunkSize {
+			size = DefaultChunkSize
+		}
+		buf := getChunkBuffer(size)
+		chunk := (*buf)[:size]
+
+		n, err := io.ReadFull(data, chunk)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			putChunkBuffer(buf)
+			return nil, c.transferError(path, state.Offset, -1, err)
+		}
+		if allowPut && err != nil && state.UploadId == "" {
+			meta, perr := c.PutFileWithOptions(path, bytes.NewReader(chunk[:n]), int64(n), opts)
+			putChunkBuffer(buf)
+			return meta, c.transferError(path, 0, int64(n), perr)
+		}
+		if n == 0 && state.UploadId != "" {
+			putChunkBuffer(buf)
+			break
+		}
+
+		if done == nil {
+			done = c.trackUpload(path)
+		}
+		next, uerr := c.sendChunk(&state, chunk[:n], tuned)
+		putChunkBuffer(buf)
+		if uerr != nil {
+			done(nil, state.Offset, uerr)
+			return nil, c.transferError(path, state.Offset, -1, uerr)
+		}
+		state = *next
+
+		if err != nil {
+			break
+		}
+	}
+
+	meta, err := c.CommitChunkedUploadWithOptions(path, state.UploadId, opts)
+	done(meta, state.Offset, err)
+	return meta, c.transferError(path, state.Offset, state.Offset, err)
+}
+
+// UploadFromPath uploads the local file at localPath to remotePath as controlled
+// by opts, which may be nil, as in PutFileWithOptions. If opts does not specify
+// a ClientMTime, the local file's modification time is used. Small files are sent with a single request,
+// larger ones with the chunked upload API.
+func (c *Client) UploadFromPath(localPath, remotePath string, opts *UploadOptions) (*Metadata, error) {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", localPath)
+	}
+
+	o := opts.withMTime(info.ModTime())
+
+	if info.Size() <= DefaultChunkSize {
+		c, cancel := c.beginTransfer()
+		defer cancel()
+		meta, err := c.PutFileWithOptions(remotePath, f, info.Size(), o)
+		return meta, c.transferError(remotePath, 0, info.Size(), err)
+	}
+	return c.upload(remotePath, f, 0, o, false)
+}
+
+// DownloadToPath downloads the file at remotePath to localPath. The data is
+// written to a temporary file in the destination directory, synced, and then
+// renamed into place, so localPath is never left partially written.
+func (c *Client) DownloadToPath(remotePath, localPath string) (*Metadata, error) {
+	return c.downloadToPath(remotePath, "", localPath, nil)
+}
+
+// downloadToPath implements DownloadToPath, recording progress in t if it is
+// not nil.
+func (c *Client) downloadToPath(remotePath, rev, localPath string, t *Transfer) (*Metadata, error) {
+	c, cancel := c.beginTransfer()
+	defer cancel()
+	body, meta, err := c.GetFile(remotePath, rev)
+	if err != nil {
+		return nil, c.transferError(remotePath, 0, -1, err)
+	}
+	defer drainAndClose(body)
+
+	f, err := ioutil.TempFile(filepath.Dir(localPath), "."+filepath.Base(localPath)+".tmp")
+	if err != nil {
+		return nil, err
+	}
+	tmp := f.Name()
+
+	var w io.Writer = f
+	if t != nil {
+		if meta != nil {
+			atomic.StoreInt64(&t.size, meta.Bytes)
+		}
+		atomic.StoreInt64(&t.transferred, 0)
+		w = progressWriter{f, t}
+	}
+	n, err := copyBuffered(w, body)
+	err = c.transferError(remotePath, n, metaSize(meta), err)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp, 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp, localPath)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return nil, err
+	}
+	return meta, nil
+}
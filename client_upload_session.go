@@ -0,0 +1,112 @@
+package dropbox
+
+import (
+	"io"
+)
+
+// An UploadSessionCursor identifies a version 2 upload session and the offset
+// at which the next data is appended.
+type UploadSessionCursor struct {
+	SessionID string `json:"session_id"`
+	Offset    int64  `json:"offset"`
+}
+
+// commitInfo describes how an uploaded file is committed in the version 2 API.
+type commitInfo struct {
+	Path           string      `json:"path"`
+	Mode           interface{} `json:"mode"`
+	Autorename     bool        `json:"autorename"`
+	ClientModified string      `json:"client_modified,omitempty"`
+}
+
+func newCommitInfo(path string, opts *UploadOptions) commitInfo {
+	ci := commitInfo{Path: v2Path(path), Mode: Tag{"add"}}
+	if opts == nil {
+		return ci
+	}
+	ci.Autorename = opts.Autorename
+	switch {
+	case opts.ParentRev != "":
+		ci.Mode = struct {
+			Tag    string `json:".tag"`
+			Update string `json:"update"`
+		}{"update", opts.ParentRev}
+	case opts.Overwrite:
+		ci.Mode = Tag{"overwrite"}
+	}
+	if !opts.ClientMTime.IsZero() {
+		ci.ClientModified = opts.ClientMTime.UTC().Format("2006-01-02T15:04:05Z")
+	}
+	return ci
+}
+
+// StartUploadSession begins a version 2 upload session, sending size bytes of
+// data as its first part (which may be empty). If concurrent is true, parts may
+// be appended in parallel, but every part except the last must then be a
+// multiple of 4 MiB. The returned cursor is positioned after the data.
+func (c *Client) StartUploadSession(data io.Reader, size int64, concurrent bool) (*UploadSessionCursor, error) {
+	arg := struct {
+		SessionType *Tag `json:"session_type,omitempty"`
+	}{}
+	if concurrent {
+		arg.SessionType = &Tag{"concurrent"}
+	}
+	var res struct {
+		SessionID string `json:"session_id"`
+	}
+	if err := c.contentUpload("/files/upload_session/start", arg, data, size, &res); err != nil {
+		return nil, err
+	}
+	return &UploadSessionCursor{res.SessionID, size}, nil
+}
+
+// AppendUploadSession appends size bytes of data to an upload session at the
+// cursor's offset, and returns a cursor positioned after the data. If close is
+// true no more data may be appended.
+func (c *Client) AppendUploadSession(cursor *UploadSessionCursor, data io.Reader, size int64, close bool) (*UploadSessionCursor, error) {
+	arg := struct {
+		Cursor *UploadSessionCursor `json:"cursor"`
+		Close  bool                 `json:"close"`
+	}{cursor, close}
+	if err := c.contentUpload("/files/upload_session/append_v2", arg, data, size, nil); err != nil {
+		return nil, err
+	}
+	return &UploadSessionCursor{cursor.SessionID, cursor.Offset + size}, nil
+}
+
+// FinishUploadSession sends any final size bytes of data, and commits the
+// upload session to path, as controlled by opts, which may be nil.
+func (c *Client) FinishUploadSession(cursor *UploadSessionCursor, path string, opts *UploadOptions, data io.Reader, size int64) (meta *FileMetadata, err error) {
+	arg := struct {
+		Cursor *UploadSessionCursor `json:"cursor"`
+		Commit commitInfo           `json:"commit"`
+	}{cursor, newCommitInfo(path, opts)}
+	err = c.contentUpload("/files/upload_session/finish", arg, data, size, &meta)
+	return
+}
+
+// An UploadSessionFinish describes one upload session to commit with
+// FinishUploadSessionBatch. The session must have been closed.
+type UploadSessionFinish struct {
+	Cursor  *UploadSessionCursor
+	Path    string
+	Options *UploadOptions
+}
+
+// FinishUploadSessionBatch commits many closed upload sessions at once, which
+// avoids the lock contention of committing them individually. It waits for the
+// asynchronous job to complete and returns the result of each entry, in order.
+func (c *Client) FinishUploadSessionBatch(entries []UploadSessionFinish) ([]BatchResult, error) {
+	type finishArg struct {
+		Cursor *UploadSessionCursor `json:"cursor"`
+		Commit commitInfo           `json:"commit"`
+	}
+	arg := struct {
+		Entries []finishArg `json:"entries"`
+	}{make([]finishArg, len(entries))}
+	for i, e := range entries {
+		arg.Entries[i] = finishArg{e.Cursor, newCommitInfo(e.Path, e.Options)}
+	}
+
+	return c.runBatch("/files/upload_session/finish_batch", "/files/upload_session/finish_batch/check", arg)
+}
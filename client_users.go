@@ -0,0 +1,74 @@
+package dropbox
+
+// A Name holds the various forms of a user's name.
+type Name struct {
+	GivenName       string `json:"given_name"`
+	Surname         string `json:"surname"`
+	FamiliarName    string `json:"familiar_name"`
+	DisplayName     string `json:"display_name"`
+	AbbreviatedName string `json:"abbreviated_name"`
+}
+
+// A RootInfo describes the namespaces of a user.
+type RootInfo struct {
+	Tag             string `json:".tag"` // "user" or "team"
+	RootNamespaceID string `json:"root_namespace_id"`
+	HomeNamespaceID string `json:"home_namespace_id"`
+	HomePath        string `json:"home_path,omitempty"`
+}
+
+// A FullTeam describes the team a user belongs to.
+type FullTeam struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// A FullAccount represents the user's account information as returned by the
+// version 2 API.
+type FullAccount struct {
+	AccountID       string    `json:"account_id"`
+	Name            Name      `json:"name"`
+	Email           string    `json:"email"`
+	EmailVerified   bool      `json:"email_verified"`
+	Disabled        bool      `json:"disabled"`
+	Locale          string    `json:"locale"`
+	ReferralLink    string    `json:"referral_link"`
+	IsPaired        bool      `json:"is_paired"`
+	AccountType     Tag       `json:"account_type"` // "basic", "pro" or "business"
+	RootInfo        RootInfo  `json:"root_info"`
+	Country         string    `json:"country"`
+	ProfilePhotoURL string    `json:"profile_photo_url"`
+	Team            *FullTeam `json:"team"`
+	TeamMemberID    string    `json:"team_member_id"`
+}
+
+// A SpaceAllocation describes the space available to a user. For individual
+// accounts (Tag "individual") only Allocated is set; for team members (Tag
+// "team") Allocated and Used are the team's totals and the remaining fields
+// describe the user's share of it.
+type SpaceAllocation struct {
+	Tag                           string `json:".tag"`
+	Allocated                     uint64 `json:"allocated"`
+	Used                          uint64 `json:"used"`
+	UserWithinTeamSpaceAllocated  uint64 `json:"user_within_team_space_allocated"`
+	UserWithinTeamSpaceLimitType  Tag    `json:"user_within_team_space_limit_type"`
+	UserWithinTeamSpaceUsedCached uint64 `json:"user_within_team_space_used_cached"`
+}
+
+// A SpaceUsage represents the space used by a user and their allocation.
+type SpaceUsage struct {
+	Used       uint64          `json:"used"`
+	Allocation SpaceAllocation `json:"allocation"`
+}
+
+// CurrentAccount performs the version 2 users/get_current_account call.
+func (c *Client) CurrentAccount() (account *FullAccount, err error) {
+	err = c.rpc("/users/get_current_account", nil, &account)
+	return
+}
+
+// SpaceUsage performs the version 2 users/get_space_usage call.
+func (c *Client) SpaceUsage() (usage *SpaceUsage, err error) {
+	err = c.rpc("/users/get_space_usage", nil, &usage)
+	return
+}
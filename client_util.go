@@ -1,14 +1,18 @@
 package dropbox
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"log"
 	"net/http"
 	"net/url"
 	"path"
+	"reflect"
+	"strings"
 	"time"
 )
 
@@ -19,23 +23,45 @@ type Time struct {
 	time.Time
 }
 
-// UnmarshalJSON exists to implement the json.Unmarshaller interface
+// timeLayouts are the formats accepted for a Time, in order of preference.
+// Dropbox normally uses RFC1123Z, but variants have been seen.
+var timeLayouts = []string{
+	time.RFC1123Z,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"Mon, 02 Jan 2006 15:04:05 -07:00",
+	time.RFC3339,
+}
+
+// UnmarshalJSON exists to implement the json.Unmarshaller interface. A null
+// or empty value results in the zero time.
 func (t *Time) UnmarshalJSON(data []byte) error {
-	var str string
+	var str *string
 	if err := json.Unmarshal(data, &str); err != nil {
 		return err
 	}
+	if str == nil || strings.TrimSpace(*str) == "" {
+		t.Time = time.Time{}
+		return nil
+	}
 
-	t2, err := time.Parse(time.RFC1123Z, str)
-	if err != nil {
-		return err
+	s := strings.TrimSpace(*str)
+	for _, layout := range timeLayouts {
+		if t2, err := time.Parse(layout, s); err == nil {
+			t.Time = t2
+			return nil
+		}
 	}
-	t.Time = t2
-	return nil
+	return fmt.Errorf("cannot parse time %q", s)
 }
 
-// MarshalJSON exists to implement the json.Marshaller interface
-func (t *Time) MarshalJSON() ([]byte, error) {
+// MarshalJSON exists to implement the json.Marshaller interface. The zero
+// time is encoded as null, so it survives a round trip unchanged.
+func (t Time) MarshalJSON() ([]byte, error) {
+	if t.IsZero() {
+		return []byte("null"), nil
+	}
 	return json.Marshal(t.Format(time.RFC1123Z))
 }
 
@@ -56,6 +82,35 @@ type Metadata struct {
 	MimeType    string     `json:"mime_type"`
 	Revision    uint64     `json:"revision"`
 	Contents    []Metadata `json:"contents"`
+
+	IsDeleted            bool          `json:"is_deleted,omitempty"`
+	ReadOnly             bool          `json:"read_only,omitempty"`
+	SharedFolder         *SharedFolder `json:"shared_folder,omitempty"`
+	ParentSharedFolderID string        `json:"parent_shared_folder_id,omitempty"`
+	Modifier             *User         `json:"modifier,omitempty"`
+}
+
+// A User identifies a Dropbox user, such as the last modifier of a file in a
+// shared folder.
+type User struct {
+	UID         uint64 `json:"uid"`
+	DisplayName string `json:"display_name"`
+	SameTeam    bool   `json:"same_team,omitempty"`
+	MemberID    string `json:"member_id,omitempty"`
+}
+
+// A SharedFolder is included in the Metadata of the root of a shared folder.
+// Membership is only filled in when requested.
+type SharedFolder struct {
+	SharedFolderID string                   `json:"shared_folder_id"`
+	Membership     []SharedFolderMembership `json:"membership,omitempty"`
+}
+
+// A SharedFolderMembership describes a user's access to a shared folder.
+type SharedFolderMembership struct {
+	User       User   `json:"user"`
+	AccessType string `json:"access_type"`
+	Active     bool   `json:"active"`
 }
 
 // An Entry is a [path, metadata] pair that represents a file change in
@@ -70,9 +125,30 @@ type Entry struct {
 // with different types in its two fields (ie: a tuple), and some
 // work needs to be done to convert it into a struct, since Go doesn't
 // support either arrays with different types in the fields or tuples.
+//
+// A null metadata value, or a missing one, marks a deletion. Elements beyond
+// the second are ignored.
 func (e *Entry) UnmarshalJSON(data []byte) error {
-	els := []interface{}{&e.Path, &e.Meta}
-	return json.Unmarshal(data, &els)
+	var els []json.RawMessage
+	if err := json.Unmarshal(data, &els); err != nil {
+		return fmt.Errorf("entry is not a [path, metadata] array: %w", err)
+	}
+	if len(els) == 0 {
+		return errors.New("entry has no path")
+	}
+
+	var p *string
+	if err := json.Unmarshal(els[0], &p); err != nil || p == nil {
+		return fmt.Errorf("entry path %s is not a string", els[0])
+	}
+	e.Path = *p
+	e.Meta = nil
+	if len(els) > 1 {
+		if err := json.Unmarshal(els[1], &e.Meta); err != nil {
+			return fmt.Errorf("metadata of entry %q: %w", e.Path, err)
+		}
+	}
+	return nil
 }
 
 // MarshalJSON exists to implement the json.Marshaller interface properly
@@ -89,6 +165,27 @@ type Delta struct {
 	HasMore bool    `json:"has_more"`
 }
 
+// UnmarshalJSON decodes a delta, reporting which entry is at fault if one of
+// them can't be decoded.
+func (d *Delta) UnmarshalJSON(data []byte) error {
+	type plainDelta Delta
+	var raw struct {
+		plainDelta
+		Entries []json.RawMessage `json:"entries"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*d = Delta(raw.plainDelta)
+	d.Entries = make([]Entry, len(raw.Entries))
+	for i, el := range raw.Entries {
+		if err := json.Unmarshal(el, &d.Entries[i]); err != nil {
+			return fmt.Errorf("delta entry %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
 // A Share represents a resource in the user's dropbox which can be accessed
 // through an external URL with no authentication needed. Perfect for embedding
 // into an email, sending as a link, or downloading without involving your own
@@ -132,12 +229,65 @@ type ChunkedUpload struct {
 type APIError struct {
 	Code    int
 	Message string `json:"error"`
+
+	// Detail holds the structured error of a version 2 API call, whose
+	// summary is stored in Message.
+	Detail json.RawMessage `json:"-"`
+
+	// Status, Header and Body are taken from the response, so callers can
+	// inspect it without repeating the request. Header has cookies removed
+	// and Body is truncated to MaxErrorBody bytes.
+	Status string      `json:"-"`
+	Header http.Header `json:"-"`
+	Body   []byte      `json:"-"`
+}
+
+// MaxErrorBody is the number of bytes of an error response kept in APIError.
+const MaxErrorBody = 64 << 10
+
+// UnmarshalJSON decodes both version 1 errors, where "error" is a message, and
+// version 2 errors, where "error" is a structure described by "error_summary".
+func (ae *APIError) UnmarshalJSON(data []byte) error {
+	var body struct {
+		Error   json.RawMessage `json:"error"`
+		Summary string          `json:"error_summary"`
+	}
+	if err := json.Unmarshal(data, &body); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body.Error, &ae.Message); err != nil {
+		ae.Message = body.Summary
+		ae.Detail = body.Error
+	}
+	return nil
 }
 
 func (ae *APIError) Error() string {
 	return fmt.Sprintf("Dropbox API Error(%d): %s", ae.Code, ae.Message)
 }
 
+// Sentinel errors which API errors can be matched against with errors.Is.
+var (
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrNotFound     = errors.New("not found")
+	ErrOverQuota    = errors.New("over quota")
+)
+
+// Unwrap returns the sentinel error matching the kind of the error, if any.
+func (ae *APIError) Unwrap() error {
+	switch {
+	case ae.Code == http.StatusUnauthorized:
+		return ErrUnauthorized
+	case ae.Code == http.StatusNotFound,
+		ae.Code == http.StatusConflict && strings.Contains(ae.Message, "not_found/"):
+		return ErrNotFound
+	case ae.Code == http.StatusInsufficientStorage,
+		ae.Code == http.StatusConflict && strings.Contains(ae.Message, "insufficient_space/"):
+		return ErrOverQuota
+	}
+	return nil
+}
+
 func (c *Client) filePath(p string) string {
 	return path.Clean(path.Join("/", string(c.root), p))
 }
@@ -157,40 +307,132 @@ func checkResponse(response *http.Response, err error) (*http.Response, error) {
 }
 
 func (c *Client) put(urlStr string, params url.Values, body io.Reader, contentLength int64) (*http.Response, error) {
-	if err := c.signParam("PUT", urlStr, params); err != nil {
-		return nil, err
+	return c.do("PUT", urlStr, params, body, contentLength)
+}
+
+func (c *Client) postForm(urlStr string, params url.Values) (*http.Response, error) {
+	return c.do("POST", urlStr, params, nil, 0)
+}
+
+func (c *Client) get(urlStr string, params url.Values) (*http.Response, error) {
+	return c.do("GET", urlStr, params, nil, 0)
+}
+
+// newRequest builds an authorized request for the given API call. POST requests
+// without a body send params as a form, all others send them in the URL.
+func (c *Client) newRequest(method, urlStr string, params url.Values, body io.Reader, contentLength int64) (*http.Request, error) {
+	v2 := isV2(urlStr)
+	urlStr = c.BaseURLs.rewrite(urlStr)
+	if params == nil {
+		params = make(url.Values)
+	}
+	if !v2 {
+		for k, v := range c.params {
+			if _, ok := params[k]; !ok {
+				params[k] = append([]string(nil), v...)
+			}
+		}
 	}
 
-	req, err := http.NewRequest("PUT", urlStr+"?"+params.Encode(), body)
-	if err != nil {
-		return nil, err
+	// Version 2 calls only accept credentials in the Authorization header.
+	querySigned := c.QuerySigning && c.OAuth2Token == "" && !v2
+	if querySigned {
+		if err := c.signParam(method, urlStr, params); err != nil {
+			return nil, err
+		}
 	}
 
-	if contentLength > 0 {
-		req.ContentLength = contentLength
+	var req *http.Request
+	var err error
+	if method == "POST" && body == nil {
+		req, err = http.NewRequest(method, urlStr, strings.NewReader(params.Encode()))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else {
+		uri := urlStr
+		if len(params) > 0 {
+			uri += "?" + params.Encode()
+		}
+		req, err = http.NewRequest(method, uri, body)
+		if err != nil {
+			return nil, err
+		}
+		if contentLength > 0 {
+			req.ContentLength = contentLength
+		}
+	}
+	if c.ctx != nil {
+		req = req.WithContext(c.ctx)
+	}
+	for k, v := range c.header {
+		if _, ok := req.Header[k]; !ok {
+			req.Header[k] = append([]string(nil), v...)
+		}
 	}
 
-	return checkResponse(c.client().Do(req))
+	if !querySigned {
+		if err := c.authorize(req, params); err != nil {
+			return nil, err
+		}
+	}
+	if c.member != "" {
+		if v2 {
+			req.Header.Set(SelectUserHeader, c.member)
+		} else {
+			req.Header.Set(TeamMemberHeader, c.member)
+		}
+	}
+	if c.pathRoot != nil {
+		req.Header.Set(PathRootHeader, c.pathRoot.header())
+	}
+	return req, nil
 }
 
-func (c *Client) postForm(urlStr string, params url.Values) (*http.Response, error) {
-	if err := c.signParam("POST", urlStr, params); err != nil {
+func (c *Client) do(method, urlStr string, params url.Values, body io.Reader, contentLength int64) (*http.Response, error) {
+	req, err := c.newRequest(method, urlStr, params, body, contentLength)
+	if err != nil {
 		return nil, err
 	}
-
-	return checkResponse(c.client().PostForm(urlStr, params))
+	return c.doRequest(req)
 }
 
-func (c *Client) get(urlStr string, params url.Values) (*http.Response, error) {
-	if err := c.signParam("GET", urlStr, params); err != nil {
-		return nil, err
+// doRequest sends a request built by newRequest. If it is refused with an
+// AuthorizationError, it is sent once more after calling the client's
+// ReauthorizeFunc, if any.
+func (c *Client) doRequest(req *http.Request) (*http.Response, error) {
+	resp, err := c.send(req)
+	if _, ok := err.(*AuthorizationError); ok {
+		return c.reauthorize(req, err)
 	}
+	return resp, err
+}
 
-	return checkResponse(c.client().Get(urlStr + "?" + params.Encode()))
+// send sends req once, subject to the limiter, breaker and retry policy.
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	if l := c.limiter(req); l != nil {
+		if err := l.Wait(req.Context()); err != nil {
+			return nil, err
+		}
+	}
+	if c.Breaker != nil && !c.Breaker.allow() {
+		return nil, ErrCircuitOpen
+	}
+	start := time.Now()
+	resp, err := c.retryPolicy().do(c.client(), req, c.observe)
+	c.audit(req, resp, err, time.Since(start))
+	if c.Breaker != nil {
+		c.Breaker.record(req, resp, err)
+	}
+	if c.Metrics != nil {
+		c.Metrics.record(req, resp, err)
+	}
+	return checkResponse(resp, err)
 }
 
 func drain(r io.Reader) error {
-	_, err := io.Copy(ioutil.Discard, r)
+	_, err := copyBuffered(ioutil.Discard, r)
 	if err == io.EOF {
 		return nil
 	}
@@ -206,6 +448,59 @@ func drainAndClose(rc io.ReadCloser) error {
 	return err2
 }
 
+// A JSONMode controls how fields of API responses which are not known to this
+// package are handled. New fields usually mean the API has changed, so the
+// stricter modes help to notice it early.
+type JSONMode int
+
+const (
+	JSONLenient    JSONMode = iota // Ignore unknown fields
+	JSONStrict                     // Fail with an error
+	JSONLogUnknown                 // Log unknown fields, and ignore them
+)
+
+// decodeJSON is like parseJSON, but decodes successful responses according to
+// the JSONMode of the Session.
+func (c *Client) decodeJSON(resp *http.Response, target interface{}) error {
+	if resp.StatusCode != http.StatusOK || target == nil || c.JSONMode == JSONLenient {
+		return parseJSON(resp, target)
+	}
+
+	if c.JSONMode == JSONStrict {
+		d := json.NewDecoder(resp.Body)
+		d.DisallowUnknownFields()
+		return d.Decode(target)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	return unmarshalMode(c.JSONMode, resp.Request.URL.Path, body, target)
+}
+
+// unmarshalMode decodes data into target according to mode. Unknown fields
+// are logged with the path of the call which returned them.
+func unmarshalMode(mode JSONMode, path string, data []byte, target interface{}) error {
+	if mode == JSONStrict {
+		d := json.NewDecoder(bytes.NewReader(data))
+		d.DisallowUnknownFields()
+		return d.Decode(target)
+	}
+	if err := json.Unmarshal(data, target); err != nil || mode != JSONLogUnknown {
+		return err
+	}
+	// Decode again into a scratch value, only to find unknown fields. The
+	// decoder stops at the first one.
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.DisallowUnknownFields()
+	scratch := reflect.New(reflect.TypeOf(target).Elem()).Interface()
+	if err := d.Decode(scratch); err != nil && strings.Contains(err.Error(), "unknown field") {
+		log.Printf("dropbox: %s: %v", path, err)
+	}
+	return nil
+}
+
 func parseJSON(resp *http.Response, target interface{}) error {
 	d := json.NewDecoder(resp.Body)
 
@@ -216,17 +511,35 @@ func parseJSON(resp *http.Response, target interface{}) error {
 		return nil
 	}
 
+	apierr, err := newAPIError(resp)
+	if err != nil {
+		return err
+	}
+	return apierr
+}
+
+// newAPIError reads the error response resp, keeping its status, headers
+// without cookies, and up to MaxErrorBody bytes of its body.
+func newAPIError(resp *http.Response) (*APIError, error) {
 	apierr := &APIError{
-		Code: resp.StatusCode,
+		Code:   resp.StatusCode,
+		Status: resp.Status,
+		Header: resp.Header.Clone(),
 	}
+	apierr.Header.Del("Set-Cookie")
 
-	if err := d.Decode(&apierr); err != nil {
-		if err != io.EOF {
-			return err
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, MaxErrorBody))
+	if err != nil {
+		return nil, err
+	}
+	apierr.Body = body
+	if len(bytes.TrimSpace(body)) > 0 {
+		if err := json.Unmarshal(body, apierr); err != nil {
+			// Some errors are reported as plain text.
+			apierr.Message = strings.TrimSpace(string(body))
 		}
 	}
-
-	return apierr
+	return apierr, nil
 }
 
 func (c *Client) putJSON(urlStr string, params url.Values, target interface{}, data io.Reader, size int64) error {
@@ -235,7 +548,7 @@ func (c *Client) putJSON(urlStr string, params url.Values, target interface{}, d
 		return err
 	}
 	defer drainAndClose(r.Body)
-	return parseJSON(r, target)
+	return c.decodeJSON(r, target)
 }
 
 func (c *Client) getJSON(urlStr string, params url.Values, target interface{}) error {
@@ -244,7 +557,7 @@ func (c *Client) getJSON(urlStr string, params url.Values, target interface{}) e
 		return err
 	}
 	defer drainAndClose(r.Body)
-	return parseJSON(r, target)
+	return c.decodeJSON(r, target)
 }
 
 func (c *Client) postFormJSON(urlStr string, params url.Values, target interface{}) error {
@@ -253,7 +566,28 @@ func (c *Client) postFormJSON(urlStr string, params url.Values, target interface
 		return err
 	}
 	defer drainAndClose(r.Body)
-	return parseJSON(r, target)
+	return c.decodeJSON(r, target)
+}
+
+// postJSON sends arg as the JSON body of a POST request, and decodes the
+// response into target.
+func (c *Client) postJSON(urlStr string, arg, target interface{}) error {
+	body, err := json.Marshal(arg)
+	if err != nil {
+		return err
+	}
+	req, err := c.newRequest("POST", urlStr, c.makeParams(false), bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	r, err := c.doRequest(req)
+	if err != nil {
+		return err
+	}
+	defer drainAndClose(r.Body)
+	return c.decodeJSON(r, target)
 }
 
 func (c *Client) fileAccess(uri string, params url.Values) (io.ReadCloser, *Metadata, error) {
@@ -261,6 +595,16 @@ func (c *Client) fileAccess(uri string, params url.Values) (io.ReadCloser, *Meta
 	if err != nil {
 		return nil, nil, err
 	}
+	return fileResponse(response)
+}
+
+// fileResponse checks the status of a response from the files or thumbnails
+// calls, and extracts the metadata from its headers, returning the body.
+func fileResponse(response *http.Response) (io.ReadCloser, *Metadata, error) {
+	if response.StatusCode != http.StatusOK && response.StatusCode != http.StatusPartialContent {
+		defer drainAndClose(response.Body)
+		return nil, nil, parseJSON(response, nil)
+	}
 	var meta *Metadata
 
 	metaStr := response.Header.Get("x-dropbox-metadata")
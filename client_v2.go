@@ -0,0 +1,187 @@
+package dropbox
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Constants to build version 2 API URLs
+const (
+	APIv2Host     = "api.dropboxapi.com"
+	ContentV2Host = "content.dropboxapi.com"
+	NotifyV2Host  = "notify.dropboxapi.com"
+
+	APIv2Prefix     = Scheme + APIv2Host + "/2"
+	ContentV2Prefix = Scheme + ContentV2Host + "/2"
+	NotifyV2Prefix  = Scheme + NotifyV2Host + "/2"
+)
+
+// Headers used by the version 2 API
+const (
+	APIArgHeader     = "Dropbox-API-Arg"
+	APIResultHeader  = "Dropbox-API-Result"
+	SelectUserHeader = "Dropbox-API-Select-User"
+)
+
+// A Tag is the discriminator of a union value in the version 2 API.
+type Tag struct {
+	Tag string `json:".tag"`
+}
+
+func isV2(urlStr string) bool {
+	return strings.HasPrefix(urlStr, APIv2Prefix) ||
+		strings.HasPrefix(urlStr, ContentV2Prefix) ||
+		strings.HasPrefix(urlStr, NotifyV2Prefix)
+}
+
+// v2Path converts a path to the form used by the version 2 API, where the
+// root folder is the empty string. Ids ("id:..."), revisions ("rev:...") and
+// namespace relative paths ("ns:...") are passed through unchanged.
+func v2Path(p string) string {
+	if strings.HasPrefix(p, "id:") || strings.HasPrefix(p, "rev:") || strings.HasPrefix(p, "ns:") {
+		return p
+	}
+	p = path.Clean("/" + p)
+	if p == "/" {
+		return ""
+	}
+	return p
+}
+
+// rpc calls a version 2 RPC endpoint, eg: "/files/get_metadata", sending arg
+// as the JSON body and decoding the JSON result into target. Either arg or
+// target may be nil.
+func (c *Client) rpc(endpoint string, arg, target interface{}) error {
+	body, err := json.Marshal(arg)
+	if err != nil {
+		return err
+	}
+	req, err := c.newRequest("POST", APIv2Prefix+endpoint, url.Values{}, bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	r, err := c.doRequest(req)
+	if err != nil {
+		return err
+	}
+	defer drainAndClose(r.Body)
+	if target == nil {
+		target = &json.RawMessage{}
+	}
+	return c.decodeJSON(r, target)
+}
+
+// apiArg encodes arg for the Dropbox-API-Arg header. Non-ASCII characters
+// must be escaped, as headers can't carry them.
+func apiArg(arg interface{}) (string, error) {
+	data, err := json.Marshal(arg)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	for _, r := range string(data) {
+		if r < utf8.RuneSelf {
+			buf.WriteRune(r)
+			continue
+		}
+		for _, u := range utf16.Encode([]rune{r}) {
+			fmt.Fprintf(&buf, `\u%04x`, u)
+		}
+	}
+	return buf.String(), nil
+}
+
+// contentUpload calls a version 2 content upload endpoint, eg: "/files/upload",
+// sending arg in the Dropbox-API-Arg header and data as the body.
+func (c *Client) contentUpload(endpoint string, arg interface{}, data io.Reader, size int64, target interface{}) error {
+	header, err := apiArg(arg)
+	if err != nil {
+		return err
+	}
+	req, err := c.newRequest("POST", ContentV2Prefix+endpoint, url.Values{}, data, size)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set(APIArgHeader, header)
+
+	r, err := c.doRequest(req)
+	if err != nil {
+		return err
+	}
+	defer drainAndClose(r.Body)
+	if target == nil {
+		target = &json.RawMessage{}
+	}
+	return c.decodeJSON(r, target)
+}
+
+// contentDownload calls a version 2 content download endpoint, eg:
+// "/files/download", sending arg in the Dropbox-API-Arg header. The result
+// is decoded from the Dropbox-API-Result header into target, and the body is
+// returned.
+func (c *Client) contentDownload(endpoint string, arg, target interface{}) (io.ReadCloser, error) {
+	header, err := apiArg(arg)
+	if err != nil {
+		return nil, err
+	}
+	req, err := c.newRequest("POST", ContentV2Prefix+endpoint, url.Values{}, http.NoBody, 0)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(APIArgHeader, header)
+
+	r, err := c.doRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	if r.StatusCode != http.StatusOK && r.StatusCode != http.StatusPartialContent {
+		defer drainAndClose(r.Body)
+		return nil, parseJSON(r, nil)
+	}
+	if target != nil {
+		if err := json.Unmarshal([]byte(r.Header.Get(APIResultHeader)), target); err != nil {
+			drainAndClose(r.Body)
+			return nil, err
+		}
+	}
+	return r.Body, nil
+}
+
+// FileMetadata represents the metadata of a file, folder or deleted entry as
+// returned by the version 2 API. Tag is one of "file", "folder" or "deleted".
+type FileMetadata struct {
+	Tag            string        `json:".tag"`
+	Name           string        `json:"name"`
+	ID             string        `json:"id"`
+	PathLower      string        `json:"path_lower"`
+	PathDisplay    string        `json:"path_display"`
+	Rev            string        `json:"rev"`
+	Size           int64         `json:"size"`
+	ClientModified time.Time     `json:"client_modified"`
+	ServerModified time.Time     `json:"server_modified"`
+	ContentHash    string        `json:"content_hash"`
+	IsDownloadable bool          `json:"is_downloadable"`
+	FileLockInfo   *FileLockInfo `json:"file_lock_info,omitempty"`
+}
+
+// IsDir reports whether the metadata is of a folder.
+func (m *FileMetadata) IsDir() bool {
+	return m.Tag == "folder"
+}
+
+// IsDeleted reports whether the metadata is of a deleted entry.
+func (m *FileMetadata) IsDeleted() bool {
+	return m.Tag == "deleted"
+}
@@ -0,0 +1,99 @@
+package dropbox
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Walk calls fn for every file and folder below the folder at root, listing
+// each folder with ListLargeFolder. Folders are visited depth first, with the
+// entries of each folder in case-insensitive order of their paths, and fn is
+// called for a folder before its contents. If fn returns ErrStopWalk, the walk
+// stops without error.
+func (c *Client) Walk(root string, fn WalkFunc) error {
+	err := c.walk(root, fn)
+	if err == ErrStopWalk {
+		return nil
+	}
+	return err
+}
+
+func (c *Client) walk(dir string, fn WalkFunc) error {
+	meta, err := c.ListLargeFolder(dir)
+	if err != nil {
+		return err
+	}
+	contents := meta.Contents
+	sort.Slice(contents, func(i, j int) bool {
+		return strings.ToLower(contents[i].Path) < strings.ToLower(contents[j].Path)
+	})
+	for i := range contents {
+		entry := &contents[i]
+		if err := fn(entry); err != nil {
+			return err
+		}
+		if entry.IsDir {
+			if err := c.walk(entry.Path, fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// DirSize returns the total size in bytes, and the number of files, of the
+// folder at path and everything inside it, which Dropbox doesn't report
+// directly. Up to concurrency folders are listed at the same time. If path is
+// a file, its own size is returned.
+func (c *Client) DirSize(path string, concurrency int) (size int64, files int, err error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, concurrency)
+	)
+
+	var visit func(dir string)
+	visit = func(dir string) {
+		defer wg.Done()
+		sem <- struct{}{}
+		meta, listErr := c.ListLargeFolder(dir)
+		<-sem
+
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			return
+		}
+		if listErr != nil {
+			err = listErr
+			return
+		}
+		if !meta.IsDir {
+			size += meta.SizeBytes()
+			files++
+			return
+		}
+		for i := range meta.Contents {
+			entry := &meta.Contents[i]
+			if entry.IsDir {
+				wg.Add(1)
+				go visit(entry.Path)
+			} else {
+				size += entry.SizeBytes()
+				files++
+			}
+		}
+	}
+
+	wg.Add(1)
+	visit(path)
+	wg.Wait()
+	if err != nil {
+		return 0, 0, err
+	}
+	return size, files, nil
+}
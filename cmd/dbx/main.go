@@ -0,0 +1,337 @@
+// Command dbx is a small command-line client for Dropbox, built on the
+// github.com/cookieo9/dropbox-go package.
+//
+// The app key and secret are read from the DROPBOX_APP_KEY and
+// DROPBOX_APP_SECRET environment variables. Run "dbx auth" once to authorize
+// the app, the access token is then stored in ~/.dbx.json. A token given in
+// DROPBOX_ACCESS_TOKEN (and DROPBOX_ACCESS_SECRET) is used instead, if set.
+// With -config, the credentials and settings are read from a JSON or TOML file
+// instead, as described by dropbox.LoadConfig.
+//
+// Usage:
+//
+//	dbx [-config file] [-root dropbox|sandbox] command [arguments]
+//
+// The commands are:
+//
+//	auth [-headless]          authorize access to your dropbox, without a browser if -headless
+//	ls [path]                 list a folder
+//	get remote [local]        download a file
+//	put local [remote]        upload a file
+//	rm path                   delete a file or folder
+//	mv from to                move a file or folder
+//	cp from to                copy a file or folder
+//	share [-short] path       print a shareable link
+//	search [path] query       search for files
+//	delta [cursor]            list changes since cursor
+//	manifest [-csv] path      print the checksums of the files in a folder
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/cookieo9/dropbox-go"
+)
+
+type command struct {
+	run   func(c *dropbox.Client, args []string) error
+	usage string
+	nargs [2]int // minimum and maximum number of arguments
+}
+
+var commands = map[string]command{
+	"ls":       {ls, "ls [path]", [2]int{0, 1}},
+	"get":      {get, "get remote [local]", [2]int{1, 2}},
+	"put":      {put, "put local [remote]", [2]int{1, 2}},
+	"rm":       {rm, "rm path", [2]int{1, 1}},
+	"mv":       {mv, "mv from to", [2]int{2, 2}},
+	"cp":       {cp, "cp from to", [2]int{2, 2}},
+	"share":    {share, "share [-short] path", [2]int{1, 2}},
+	"search":   {search, "search [path] query", [2]int{1, 2}},
+	"delta":    {delta, "delta [cursor]", [2]int{0, 1}},
+	"manifest": {manifest, "manifest [-csv] path", [2]int{1, 2}},
+}
+
+var (
+	root       = flag.String("root", "dropbox", "dropbox root to access: dropbox or sandbox")
+	configFile = flag.String("config", "", "read credentials and settings from this JSON or TOML file")
+)
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() < 1 {
+		usage()
+	}
+
+	session, err := newSession()
+	if err != nil {
+		fatal(err)
+	}
+
+	name, args := flag.Arg(0), flag.Args()[1:]
+	if name == "auth" {
+		headless := len(args) == 1 && args[0] == "-headless"
+		if len(args) > 0 && !headless {
+			fmt.Fprintln(os.Stderr, "usage: dbx auth [-headless]")
+			os.Exit(2)
+		}
+		if err := auth(session, headless); err != nil {
+			fatal(err)
+		}
+		return
+	}
+
+	cmd, ok := commands[name]
+	if !ok {
+		usage()
+	}
+	if len(args) < cmd.nargs[0] || len(args) > cmd.nargs[1] {
+		fmt.Fprintln(os.Stderr, "usage: dbx", cmd.usage)
+		os.Exit(2)
+	}
+	if !session.Authorized() {
+		fatal(fmt.Errorf("not authorized, run: dbx auth"))
+	}
+
+	client := dropbox.NewClient(session, dropbox.AccessRoot(*root))
+	if err := cmd.run(client, args); err != nil {
+		fatal(err)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: dbx [-config file] [-root dropbox|sandbox] command [arguments]")
+	fmt.Fprintln(os.Stderr, "\ncommands:\n  auth [-headless]")
+	for _, name := range []string{"ls", "get", "put", "rm", "mv", "cp", "share", "search", "delta", "manifest"} {
+		fmt.Fprintln(os.Stderr, " ", commands[name].usage)
+	}
+	os.Exit(2)
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "dbx:", err)
+	os.Exit(1)
+}
+
+func newSession() (*dropbox.Session, error) {
+	if *configFile != "" {
+		return configSession()
+	}
+	session, err := dropbox.NewSessionFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if session.Authorized() {
+		return session, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	session.TokenStore = dropbox.NewFileTokenStore(filepath.Join(home, ".dbx.json"))
+	if _, err := session.LoadAccessToken(); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// configSession returns the Session configured by the -config file, whose
+// root is used unless -root is given.
+func configSession() (*dropbox.Session, error) {
+	cfg, err := dropbox.LoadConfig(*configFile)
+	if err != nil {
+		return nil, err
+	}
+	rootSet := false
+	flag.Visit(func(f *flag.Flag) { rootSet = rootSet || f.Name == "root" })
+	if cfg.Root != "" && !rootSet {
+		*root = cfg.Root
+	}
+	return cfg.Session()
+}
+
+func auth(s *dropbox.Session, headless bool) error {
+	if err := s.ForgetAccessToken(); err != nil {
+		return err
+	}
+	if headless {
+		if err := s.AuthorizeHeadless(context.Background(), os.Stdout); err != nil {
+			return err
+		}
+	} else {
+		fmt.Println("Authorize dbx in your browser.")
+		if err := s.AuthorizeInteractive(context.Background()); err != nil {
+			return err
+		}
+	}
+	fmt.Println("Authorized.")
+	return nil
+}
+
+func printMeta(m *dropbox.Metadata) {
+	if m.IsDir {
+		fmt.Printf("%10s  %-25s  %s/\n", "-", m.Modified.Format("2006-01-02 15:04:05"), m.Path)
+		return
+	}
+	fmt.Printf("%10d  %-25s  %s\n", m.Bytes, m.Modified.Format("2006-01-02 15:04:05"), m.Path)
+}
+
+func ls(c *dropbox.Client, args []string) error {
+	p := "/"
+	if len(args) > 0 {
+		p = args[0]
+	}
+	meta, _, err := c.Metadata(p, 0, "", true, false, "")
+	if err != nil {
+		return err
+	}
+	if !meta.IsDir {
+		printMeta(meta)
+		return nil
+	}
+	for i := range meta.Contents {
+		printMeta(&meta.Contents[i])
+	}
+	return nil
+}
+
+func get(c *dropbox.Client, args []string) error {
+	local := path.Base(args[0])
+	if len(args) > 1 {
+		local = args[1]
+	}
+	if local == "-" {
+		_, _, err := c.Download(args[0], "", os.Stdout)
+		return err
+	}
+	_, err := c.DownloadToPath(args[0], local)
+	return err
+}
+
+func put(c *dropbox.Client, args []string) error {
+	remote := "/" + filepath.Base(args[0])
+	if len(args) > 1 {
+		remote = args[1]
+	}
+	opts := &dropbox.UploadOptions{Overwrite: true}
+
+	var meta *dropbox.Metadata
+	var err error
+	if args[0] == "-" {
+		meta, err = c.Upload(remote, os.Stdin, opts)
+	} else {
+		meta, err = c.UploadFromPath(args[0], remote, opts)
+	}
+	if err != nil {
+		return err
+	}
+	printMeta(meta)
+	return nil
+}
+
+func rm(c *dropbox.Client, args []string) error {
+	_, err := c.Delete(args[0])
+	return err
+}
+
+func mv(c *dropbox.Client, args []string) error {
+	meta, err := c.Move(args[1], args[0])
+	if err != nil {
+		return err
+	}
+	printMeta(meta)
+	return nil
+}
+
+func cp(c *dropbox.Client, args []string) error {
+	meta, err := c.Copy(args[1], args[0], "")
+	if err != nil {
+		return err
+	}
+	printMeta(meta)
+	return nil
+}
+
+func share(c *dropbox.Client, args []string) error {
+	short := false
+	if args[0] == "-short" {
+		short, args = true, args[1:]
+	}
+	if len(args) != 1 {
+		return fmt.Errorf("usage: dbx share [-short] path")
+	}
+	s, err := c.Shares(args[0], short)
+	if err != nil {
+		return err
+	}
+	fmt.Println(s.URL)
+	return nil
+}
+
+func search(c *dropbox.Client, args []string) error {
+	p, query := "/", args[len(args)-1]
+	if len(args) > 1 {
+		p = args[0]
+	}
+	results, err := c.Search(p, query, 0, false)
+	if err != nil {
+		return err
+	}
+	for _, m := range results {
+		printMeta(m)
+	}
+	return nil
+}
+
+func delta(c *dropbox.Client, args []string) error {
+	cursor := ""
+	if len(args) > 0 {
+		cursor = args[0]
+	}
+	for {
+		d, err := c.Delta(cursor)
+		if err != nil {
+			return err
+		}
+		if d.Reset {
+			fmt.Println("reset")
+		}
+		for _, e := range d.Entries {
+			if e.Meta == nil {
+				fmt.Printf("deleted  %s\n", e.Path)
+				continue
+			}
+			fmt.Printf("changed  %s\n", e.Meta.Path)
+		}
+		cursor = d.Cursor
+		if !d.HasMore {
+			break
+		}
+	}
+	fmt.Println("cursor:", cursor)
+	return nil
+}
+
+func manifest(c *dropbox.Client, args []string) error {
+	csv := false
+	if args[0] == "-csv" {
+		csv, args = true, args[1:]
+	}
+	if len(args) != 1 {
+		return fmt.Errorf("usage: dbx manifest [-csv] path")
+	}
+	m, err := c.Manifest(args[0])
+	if err != nil {
+		return err
+	}
+	if csv {
+		return m.WriteCSV(os.Stdout)
+	}
+	return m.WriteJSON(os.Stdout)
+}
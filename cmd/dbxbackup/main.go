@@ -0,0 +1,168 @@
+// Command dbxbackup backs up a local directory to a folder in a dropbox, or
+// restores it.
+//
+// Only files which changed since the last backup are uploaded. The app key and
+// secret are read from the DROPBOX_APP_KEY and DROPBOX_APP_SECRET environment
+// variables, and the access token from DROPBOX_ACCESS_TOKEN (and
+// DROPBOX_ACCESS_SECRET) if set, otherwise from ~/.dbx.json, as written by
+// "dbx auth".
+//
+// Usage:
+//
+//	dbxbackup [flags] local remote
+//	dbxbackup -restore [flags] remote local
+//
+// The flags are:
+//
+//	-prune          delete remote files which no longer exist locally
+//	-state file     remember file hashes in file, to skip touched but unchanged files
+//	-offline        trust the state file instead of listing the remote folder
+//	-restore        restore the remote folder into the local directory
+//	-asof time      restore files as they were at time (RFC 3339)
+//	-include list   restore only files matching these comma separated patterns
+//	-exclude list   skip files matching these comma separated patterns
+//	-verify         check the restored files once downloaded
+//	-report file    write a JSON report of the run to file
+//	-n              only report what would be done
+//	-root root      dropbox root to access: dropbox or sandbox
+//
+// The exit status is 1 if any file failed to back up or restore.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cookieo9/dropbox-go"
+	"github.com/cookieo9/dropbox-go/backup"
+)
+
+var (
+	prune   = flag.Bool("prune", false, "delete remote files which no longer exist locally")
+	state   = flag.String("state", "", "file to remember file hashes in")
+	offline = flag.Bool("offline", false, "trust the state file instead of listing the remote folder")
+	report  = flag.String("report", "", "file to write a JSON report to")
+	dryRun  = flag.Bool("n", false, "only report what would be done")
+	root    = flag.String("root", "dropbox", "dropbox root to access: dropbox or sandbox")
+
+	restore = flag.Bool("restore", false, "restore the remote folder into the local directory")
+	asOf    = flag.String("asof", "", "restore files as they were at this time (RFC 3339)")
+	include = flag.String("include", "", "restore only files matching these comma separated patterns")
+	exclude = flag.String("exclude", "", "skip files matching these comma separated patterns")
+	verify  = flag.Bool("verify", false, "check the restored files once downloaded")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: dbxbackup [flags] local remote")
+		fmt.Fprintln(os.Stderr, "       dbxbackup -restore [flags] remote local")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
+	}
+
+	session, err := newSession()
+	if err != nil {
+		fatal(err)
+	}
+	client := dropbox.NewClient(session, dropbox.AccessRoot(*root))
+
+	var r *backup.Report
+	if *restore {
+		r, err = runRestore(client)
+	} else {
+		r, err = backup.Backup(client, flag.Arg(0), flag.Arg(1), &backup.Options{
+			Prune:      *prune,
+			StateFile:  *state,
+			TrustState: *offline,
+			DryRun:     *dryRun,
+		})
+	}
+	if err != nil {
+		fatal(err)
+	}
+	if *report != "" {
+		if err := r.WriteFile(*report); err != nil {
+			fatal(err)
+		}
+	}
+
+	if *restore {
+		fmt.Printf("%d downloaded (%s), %d failed, %d unverified\n",
+			len(r.Downloaded), dropbox.FormatSize(r.Bytes), len(r.Failed), len(r.Unverified))
+	} else {
+		fmt.Printf("%d uploaded (%s), %d deleted, %d unchanged, %d failed\n",
+			len(r.Uploaded), dropbox.FormatSize(r.Bytes), len(r.Deleted), r.Unchanged, len(r.Failed))
+	}
+	for _, f := range r.Failed {
+		fmt.Fprintf(os.Stderr, "dbxbackup: %s: %s\n", f.Path, f.Error)
+	}
+	for _, p := range r.Unverified {
+		fmt.Fprintf(os.Stderr, "dbxbackup: %s: verification failed\n", p)
+	}
+	if r.Interrupted != "" {
+		fmt.Fprintln(os.Stderr, "dbxbackup: interrupted:", r.Interrupted)
+	}
+	if !r.OK() {
+		os.Exit(1)
+	}
+}
+
+func runRestore(client *dropbox.Client) (*backup.Report, error) {
+	opts := &backup.RestoreOptions{
+		Include: patterns(*include),
+		Exclude: patterns(*exclude),
+		Verify:  *verify,
+		DryRun:  *dryRun,
+	}
+	if *asOf != "" {
+		t, err := time.Parse(time.RFC3339, *asOf)
+		if err != nil {
+			return nil, err
+		}
+		opts.AsOf = t
+	}
+	return backup.Restore(client, flag.Arg(0), flag.Arg(1), opts)
+}
+
+func patterns(list string) []string {
+	if list == "" {
+		return nil
+	}
+	return strings.Split(list, ",")
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "dbxbackup:", err)
+	os.Exit(1)
+}
+
+func newSession() (*dropbox.Session, error) {
+	session, err := dropbox.NewSessionFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if session.Authorized() {
+		return session, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	session.TokenStore = dropbox.NewFileTokenStore(filepath.Join(home, ".dbx.json"))
+	ok, err := session.LoadAccessToken()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("not authorized, run: dbx auth")
+	}
+	return session, nil
+}
@@ -0,0 +1,194 @@
+package dropbox
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A Config holds the credentials and settings of a Session, so they can be
+// shared by programs through a file, see LoadConfig.
+type Config struct {
+	AppKey       string `json:"app_key"`
+	AppSecret    string `json:"app_secret"`
+	AccessToken  string `json:"access_token,omitempty"`  // OAuth v1 token, or OAuth2 token if AccessSecret is empty
+	AccessSecret string `json:"access_secret,omitempty"` // OAuth v1 token secret
+	Root         string `json:"root,omitempty"`          // "dropbox" (the default) or "sandbox"
+	Locale       string `json:"locale,omitempty"`
+	Timeout      string `json:"timeout,omitempty"` // Session.Timeout, eg: "30s"
+	Proxy        string `json:"proxy,omitempty"`   // URL of the proxy to use instead of the environment's
+}
+
+// LoadConfig reads a Config from the file filename. Files ending in ".toml"
+// are read as TOML, all others as JSON, with the keys of the fields' JSON
+// names, eg:
+//
+//	app_key = "..."
+//	app_secret = "..."
+//	access_token = "..."
+//	root = "sandbox"
+//	timeout = "5m"
+//
+// Only top-level keys with string, integer and boolean values are supported
+// in TOML files.
+func LoadConfig(filename string) (*Config, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(filepath.Ext(filename), ".toml") {
+		if data, err = tomlToJSON(data); err != nil {
+			return nil, fmt.Errorf("%s: %v", filename, err)
+		}
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return &cfg, nil
+}
+
+// Session returns a new Session configured by cfg.
+func (cfg *Config) Session() (*Session, error) {
+	if cfg.AppKey == "" || cfg.AppSecret == "" {
+		return nil, fmt.Errorf("config has no app key or secret")
+	}
+	s := NewSession(cfg.AppKey, cfg.AppSecret, nil, nil)
+	if cfg.AccessToken != "" {
+		if cfg.AccessSecret != "" {
+			s.AccessToken = &Credentials{Token: cfg.AccessToken, Secret: cfg.AccessSecret}
+		} else {
+			s.OAuth2Token = cfg.AccessToken
+		}
+	}
+	s.Locale = cfg.Locale
+	if cfg.Timeout != "" {
+		d, err := time.ParseDuration(cfg.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("config timeout: %v", err)
+		}
+		s.Timeout = d
+	}
+	if cfg.Proxy != "" {
+		u, err := url.Parse(cfg.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("config proxy: %v", err)
+		}
+		s.Proxy = http.ProxyURL(u)
+	}
+	return s, nil
+}
+
+// Client returns a new Client of a Session configured by cfg, accessing its
+// Root.
+func (cfg *Config) Client() (*Client, error) {
+	s, err := cfg.Session()
+	if err != nil {
+		return nil, err
+	}
+	root := AccessRoot(cfg.Root)
+	switch root {
+	case "":
+		root = DropboxRoot
+	case DropboxRoot, SandboxRoot:
+	default:
+		return nil, fmt.Errorf("config root %q is not dropbox or sandbox", cfg.Root)
+	}
+	return NewClient(s, root), nil
+}
+
+// tomlToJSON converts a TOML document made of top-level keys with string,
+// integer and boolean values to a JSON object.
+func tomlToJSON(data []byte) ([]byte, error) {
+	values := make(map[string]interface{})
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		if line[0] == '[' {
+			return nil, fmt.Errorf("line %d: tables are not supported", n)
+		}
+		eq := strings.IndexByte(line, '=')
+		if eq < 0 {
+			return nil, fmt.Errorf("line %d: expected key = value", n)
+		}
+		key := strings.TrimSpace(line[:eq])
+		if k, err := strconv.Unquote(key); err == nil {
+			key = k
+		}
+		value, err := tomlValue(strings.TrimSpace(line[eq+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", n, err)
+		}
+		if _, dup := values[key]; dup {
+			return nil, fmt.Errorf("line %d: duplicate key %q", n, key)
+		}
+		values[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return json.Marshal(values)
+}
+
+// tomlValue parses a string, integer or boolean, which may be followed by a
+// comment.
+func tomlValue(s string) (interface{}, error) {
+	switch {
+	case strings.HasPrefix(s, `"`):
+		end := 1
+		for ; end < len(s) && s[end] != '"'; end++ {
+			if s[end] == '\\' {
+				end++
+			}
+		}
+		if end >= len(s) {
+			return nil, fmt.Errorf("unterminated string")
+		}
+		if err := tomlTrailer(s[end+1:]); err != nil {
+			return nil, err
+		}
+		return strconv.Unquote(s[:end+1])
+	case strings.HasPrefix(s, "'"):
+		end := strings.IndexByte(s[1:], '\'')
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated string")
+		}
+		if err := tomlTrailer(s[end+2:]); err != nil {
+			return nil, err
+		}
+		return s[1 : end+1], nil
+	}
+	if i := strings.IndexByte(s, '#'); i >= 0 {
+		s = strings.TrimSpace(s[:i])
+	}
+	switch s {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	if i, err := strconv.ParseInt(strings.Replace(s, "_", "", -1), 0, 64); err == nil {
+		return i, nil
+	}
+	return nil, fmt.Errorf("unsupported value %q", s)
+}
+
+// tomlTrailer checks that only a comment follows a value.
+func tomlTrailer(s string) error {
+	s = strings.TrimSpace(s)
+	if s != "" && s[0] != '#' {
+		return fmt.Errorf("unexpected %q after value", s)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package dropbox
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestTomlToJSON(t *testing.T) {
+	tests := []struct {
+		name, toml, json string
+	}{
+		{"empty", "", `{}`},
+		{"comments and blank lines", "# comment\n\n  # indented\n", `{}`},
+		{"basic string", `key = "value"`, `{"key":"value"}`},
+		{"escapes", `key = "a\"b\\c\n\u00e9"`, `{"key":"a\"b\\c\né"}`},
+		{"hash inside string", `key = "a # b" # comment`, `{"key":"a # b"}`},
+		{"literal string", `key = 'C:\path\"x"'`, `{"key":"C:\\path\\\"x\""}`},
+		{"literal string with comment", `key = 'a#b' # c`, `{"key":"a#b"}`},
+		{"quoted key", `"app key" = "x"`, `{"app key":"x"}`},
+		{"integers", "a = 42\nb = -7\nc = 1_000\nd = 0x1f # hex", `{"a":42,"b":-7,"c":1000,"d":31}`},
+		{"booleans", "yes = true\nno = false # off", `{"no":false,"yes":true}`},
+		{"equals in value", `url = "http://h/?a=b"`, `{"url":"http://h/?a=b"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tomlToJSON([]byte(tt.toml))
+			if err != nil {
+				t.Fatalf("tomlToJSON(%q): %v", tt.toml, err)
+			}
+			if string(got) != tt.json {
+				t.Errorf("tomlToJSON(%q) = %s, want %s", tt.toml, got, tt.json)
+			}
+		})
+	}
+}
+
+func TestTomlToJSONErrors(t *testing.T) {
+	for _, toml := range []string{
+		"[table]",
+		"key",
+		`key = "unterminated`,
+		`key = 'unterminated`,
+		`key = "a" b`,
+		`key = 'a' b`,
+		"key = 1.5",
+		"key = [1, 2]",
+		"key = 1\nkey = 2",
+	} {
+		if got, err := tomlToJSON([]byte(toml)); err == nil {
+			t.Errorf("tomlToJSON(%q) = %s, want an error", toml, got)
+		}
+	}
+}
+
+func TestLoadConfigTOML(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "dropbox.toml")
+	data := "app_key = \"key\"\napp_secret = 'secret'\ntimeout = \"30s\" # per request\n"
+	if err := ioutil.WriteFile(filename, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfig(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.AppKey != "key" || cfg.AppSecret != "secret" || cfg.Timeout != "30s" {
+		t.Errorf("LoadConfig = %+v", cfg)
+	}
+}
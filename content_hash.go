@@ -0,0 +1,48 @@
+package dropbox
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+)
+
+// ContentHashBlockSize is the size of the blocks hashed separately by
+// ContentHash.
+const ContentHashBlockSize = 4 * 1024 * 1024
+
+// ContentHash returns the hash of the data read from r as Dropbox computes it
+// for the ContentHash of FileMetadata: the hex SHA-256 hash of the
+// concatenated SHA-256 hashes of every ContentHashBlockSize bytes of data. It
+// allows local files to be compared with remote ones without downloading them.
+func ContentHash(r io.Reader) (string, error) {
+	overall := sha256.New()
+	block := sha256.New()
+	buf := getCopyBuffer()
+	defer putCopyBuffer(buf)
+	for {
+		n, err := io.CopyBuffer(block, io.LimitReader(r, ContentHashBlockSize), *buf)
+		if err != nil {
+			return "", err
+		}
+		if n == 0 {
+			break
+		}
+		overall.Write(block.Sum(nil))
+		block.Reset()
+		if n < ContentHashBlockSize {
+			break
+		}
+	}
+	return hex.EncodeToString(overall.Sum(nil)), nil
+}
+
+// ContentHashFile returns the ContentHash of the local file name.
+func ContentHashFile(name string) (string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return ContentHash(f)
+}
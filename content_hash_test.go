@@ -0,0 +1,73 @@
+package dropbox
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"testing/iotest"
+)
+
+// The expected hashes were computed independently of this package, following
+// the algorithm Dropbox documents for content_hash.
+func TestContentHash(t *testing.T) {
+	pattern := make([]byte, 2*ContentHashBlockSize+100)
+	for i := range pattern {
+		pattern[i] = byte(i % 251)
+	}
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		// No blocks: the hash of nothing.
+		{"empty", nil, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+		{"one byte", []byte("a"), "bf5d3affb73efd2ec6c36ad3112dd933efed63c4e1cbffcfa88e2759c144f2d8"},
+		// A full block isn't followed by the hash of an empty one.
+		{"one block", make([]byte, ContentHashBlockSize), "c7e946d101855255d919ef0c70718633adf77d3dfb3adeeecf5d0cb4e951be58"},
+		{"block and a byte", make([]byte, ContentHashBlockSize+1), "14a4d47f23a30177885d9820122f17d2d3a55fe63f7f5c27b95f689e0b2accd6"},
+		{"three blocks", pattern, "47d18a15e2737a859f171431e816cf28e3d2dc0a8aeefe41ebb486b670199779"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ContentHash(bytes.NewReader(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("ContentHash = %s, want %s", got, tt.want)
+			}
+			// Short reads must not change the block boundaries.
+			got, err = ContentHash(iotest.HalfReader(bytes.NewReader(tt.data)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("ContentHash with short reads = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContentHashFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "a.txt")
+	if err := ioutil.WriteFile(name, []byte("a"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ContentHashFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "bf5d3affb73efd2ec6c36ad3112dd933efed63c4e1cbffcfa88e2759c144f2d8"; got != want {
+		t.Errorf("ContentHashFile = %s, want %s", got, want)
+	}
+	if _, err := ContentHashFile(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("ContentHashFile of a missing file succeeded")
+	}
+}
+
+func TestContentHashReadError(t *testing.T) {
+	if _, err := ContentHash(iotest.ErrReader(iotest.ErrTimeout)); err != iotest.ErrTimeout {
+		t.Errorf("ContentHash error = %v, want %v", err, iotest.ErrTimeout)
+	}
+}
@@ -0,0 +1,271 @@
+package dropbox
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// Parameters for encrypting credentials.
+const (
+	PassphraseIterations = 100000 // PBKDF2 iterations used to derive a key from a passphrase
+	passphraseSaltSize   = 16
+	passphraseKeySize    = 32
+
+	sealedWithKey        = 1
+	sealedWithPassphrase = 2
+)
+
+// ErrBadCredentialData is returned when encrypted credentials cannot be
+// decrypted, either because the data is corrupt or the key is wrong.
+var ErrBadCredentialData = errors.New("invalid or corrupt encrypted credentials")
+
+// SealCredentials encrypts cred with AES-GCM using the given key, which must
+// be 16, 24 or 32 bytes long. The result is safe to write to disk.
+func SealCredentials(cred *Credentials, key []byte) ([]byte, error) {
+	return seal(cred, key, []byte{sealedWithKey})
+}
+
+// OpenCredentials decrypts data produced by SealCredentials using key.
+func OpenCredentials(data, key []byte) (*Credentials, error) {
+	if len(data) < 1 || data[0] != sealedWithKey {
+		return nil, ErrBadCredentialData
+	}
+	return open(data[1:], key, data[:1])
+}
+
+// SealCredentialsWithPassphrase encrypts cred with a key derived from the given
+// passphrase using PBKDF2-SHA256 and a random salt, which is stored alongside
+// the ciphertext.
+func SealCredentialsWithPassphrase(cred *Credentials, passphrase string) ([]byte, error) {
+	header := make([]byte, 1+passphraseSaltSize)
+	header[0] = sealedWithPassphrase
+	if _, err := io.ReadFull(rand.Reader, header[1:]); err != nil {
+		return nil, err
+	}
+	key := pbkdf2SHA256([]byte(passphrase), header[1:], PassphraseIterations, passphraseKeySize)
+	return seal(cred, key, header)
+}
+
+// OpenCredentialsWithPassphrase decrypts data produced by
+// SealCredentialsWithPassphrase.
+func OpenCredentialsWithPassphrase(data []byte, passphrase string) (*Credentials, error) {
+	if len(data) < 1+passphraseSaltSize || data[0] != sealedWithPassphrase {
+		return nil, ErrBadCredentialData
+	}
+	header := data[:1+passphraseSaltSize]
+	key := pbkdf2SHA256([]byte(passphrase), header[1:], PassphraseIterations, passphraseKeySize)
+	return open(data[len(header):], key, header)
+}
+
+// WriteEncryptedCredentials encrypts cred with the given passphrase and writes
+// it to filename, readable only by the current user.
+func WriteEncryptedCredentials(filename string, cred *Credentials, passphrase string) error {
+	data, err := SealCredentialsWithPassphrase(cred, passphrase)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filename, data, 0600)
+}
+
+// ReadEncryptedCredentials reads and decrypts credentials written by
+// WriteEncryptedCredentials.
+func ReadEncryptedCredentials(filename string, passphrase string) (*Credentials, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return OpenCredentialsWithPassphrase(data, passphrase)
+}
+
+// seal encrypts cred, and returns header, the nonce, and the ciphertext
+// concatenated. The header is authenticated but not encrypted.
+func seal(cred *Credentials, key, header []byte) ([]byte, error) {
+	plain, err := json.Marshal(cred)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	out := append([]byte{}, header...)
+	out = append(out, nonce...)
+	return aead.Seal(out, nonce, plain, header), nil
+}
+
+func open(data, key, header []byte) (*Credentials, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, ErrBadCredentialData
+	}
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+	plain, err := aead.Open(nil, nonce, ciphertext, header)
+	if err != nil {
+		return nil, ErrBadCredentialData
+	}
+
+	var cred Credentials
+	if err := json.Unmarshal(plain, &cred); err != nil {
+		return nil, ErrBadCredentialData
+	}
+	return &cred, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// pbkdf2SHA256 implements PBKDF2 (RFC 2898) with HMAC-SHA256.
+func pbkdf2SHA256(password, salt []byte, iter, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	hashLen := prf.Size()
+	numBlocks := (keyLen + hashLen - 1) / hashLen
+
+	var buf [4]byte
+	dk := make([]byte, 0, numBlocks*hashLen)
+	u := make([]byte, hashLen)
+	for block := 1; block <= numBlocks; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.BigEndian.PutUint32(buf[:], uint32(block))
+		prf.Write(buf[:])
+		dk = prf.Sum(dk)
+		t := dk[len(dk)-hashLen:]
+		copy(u, t)
+
+		for n := 2; n <= iter; n++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for i := range u {
+				t[i] ^= u[i]
+			}
+		}
+	}
+	return dk[:keyLen]
+}
+
+// An Encryptor encrypts and decrypts small secrets, such as credentials, for
+// an EncryptedTokenStore. It lets tokens be wrapped by a key management
+// service (eg: AWS KMS, GCP KMS or Vault transit) before they are persisted.
+// Context is not secret but is bound to the ciphertext, so Decrypt must fail
+// unless it is given the context used to Encrypt; key management services
+// call it encryption context or associated data.
+type Encryptor interface {
+	Encrypt(plaintext, context []byte) ([]byte, error)
+	Decrypt(ciphertext, context []byte) ([]byte, error)
+}
+
+// An AESEncryptor is an Encryptor using AES-GCM with a local key.
+type AESEncryptor struct {
+	aead cipher.AEAD
+}
+
+// NewAESEncryptor creates an AESEncryptor with the given key, which must be 16,
+// 24 or 32 bytes long.
+func NewAESEncryptor(key []byte) (*AESEncryptor, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	return &AESEncryptor{aead}, nil
+}
+
+// Encrypt returns a random nonce followed by the sealed plaintext.
+func (e *AESEncryptor) Encrypt(plaintext, context []byte) ([]byte, error) {
+	nonce := make([]byte, e.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return e.aead.Seal(nonce, nonce, plaintext, context), nil
+}
+
+// Decrypt opens data produced by Encrypt.
+func (e *AESEncryptor) Decrypt(ciphertext, context []byte) ([]byte, error) {
+	if len(ciphertext) < e.aead.NonceSize() {
+		return nil, ErrBadCredentialData
+	}
+	n := e.aead.NonceSize()
+	plain, err := e.aead.Open(nil, ciphertext[:n], ciphertext[n:], context)
+	if err != nil {
+		return nil, ErrBadCredentialData
+	}
+	return plain, nil
+}
+
+// encryptedTokenPrefix marks the tokens stored by an EncryptedTokenStore.
+const encryptedTokenPrefix = "enc1:"
+
+// An EncryptedTokenStore is a TokenStore which encrypts credentials with an
+// Encryptor before passing them to another TokenStore. The stored credentials
+// have the ciphertext as their token, and the account name is used as the
+// encryption context, so entries can't be swapped between accounts.
+type EncryptedTokenStore struct {
+	Store     TokenStore
+	Encryptor Encryptor
+}
+
+// Get returns the credentials stored for account. Credentials which weren't
+// stored by an EncryptedTokenStore are rejected with ErrBadCredentialData.
+func (e *EncryptedTokenStore) Get(account string) (*Credentials, error) {
+	stored, err := e.Store.Get(account)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(stored.Token, encryptedTokenPrefix) {
+		return nil, ErrBadCredentialData
+	}
+	ciphertext, err := base64.RawURLEncoding.DecodeString(stored.Token[len(encryptedTokenPrefix):])
+	if err != nil {
+		return nil, ErrBadCredentialData
+	}
+	plain, err := e.Encryptor.Decrypt(ciphertext, []byte(account))
+	if err != nil {
+		return nil, err
+	}
+	var cred Credentials
+	if err := json.Unmarshal(plain, &cred); err != nil {
+		return nil, ErrBadCredentialData
+	}
+	return &cred, nil
+}
+
+// Put encrypts cred and stores it for account.
+func (e *EncryptedTokenStore) Put(account string, cred *Credentials) error {
+	plain, err := json.Marshal(cred)
+	if err != nil {
+		return err
+	}
+	ciphertext, err := e.Encryptor.Encrypt(plain, []byte(account))
+	if err != nil {
+		return err
+	}
+	return e.Store.Put(account, &Credentials{Token: encryptedTokenPrefix + base64.RawURLEncoding.EncodeToString(ciphertext)})
+}
+
+// Delete removes the credentials for account.
+func (e *EncryptedTokenStore) Delete(account string) error {
+	return e.Store.Delete(account)
+}
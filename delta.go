@@ -0,0 +1,146 @@
+package dropbox
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A ChangeKind describes how an entry in the dropbox changed.
+type ChangeKind int
+
+// Kinds of change derived from delta entries.
+const (
+	Added         ChangeKind = iota // A file appeared
+	Modified                        // An existing file changed
+	Deleted                         // A file or folder was removed
+	FolderCreated                   // A folder appeared
+)
+
+var changeKindNames = []string{"added", "modified", "deleted", "folder-created"}
+
+func (k ChangeKind) String() string {
+	if k >= 0 && int(k) < len(changeKindNames) {
+		return changeKindNames[k]
+	}
+	return fmt.Sprintf("ChangeKind(%d)", int(k))
+}
+
+// A ChangeEvent is a typed view of a single delta Entry.
+type ChangeEvent struct {
+	Kind ChangeKind
+	Path string    // Lower-cased path of the entry, as in Entry.Path
+	Meta *Metadata // New metadata, nil for Deleted
+
+	// OldPath is the lower-cased path a file was moved from, if an Added
+	// event could be matched with a deletion of the same revision in the
+	// same page of changes. The Deleted event is reported as well.
+	OldPath string
+}
+
+// A ChangeClassifier converts pages of delta entries into ChangeEvents. It
+// remembers the revision of every file it has seen, so that changes to known
+// files are reported as Modified rather than Added. A single classifier must be
+// fed every page of a delta sequence in order. The zero value is ready to use.
+type ChangeClassifier struct {
+	known map[string]string // path -> rev, "" for folders
+}
+
+// Classify returns the events for a page of changes. If the page is a reset
+// all remembered state is discarded first, as required by the delta
+// semantics, so every entry of the page is reported as new.
+func (cc *ChangeClassifier) Classify(delta *Delta) []ChangeEvent {
+	if cc.known == nil || delta.Reset {
+		cc.known = make(map[string]string)
+	}
+
+	events := make([]ChangeEvent, 0, len(delta.Entries))
+	deletedRevs := make(map[string]string)
+	for _, e := range delta.Entries {
+		path := NormalizePath(e.Path)
+		if e.Meta == nil {
+			// Deleting a folder deletes everything inside it.
+			for p, rev := range cc.known {
+				if p == path || strings.HasPrefix(p, path+"/") {
+					if rev != "" {
+						deletedRevs[rev] = p
+					}
+					delete(cc.known, p)
+				}
+			}
+			events = append(events, ChangeEvent{Kind: Deleted, Path: path})
+			continue
+		}
+
+		ev := ChangeEvent{Path: path, Meta: e.Meta}
+		_, exists := cc.known[path]
+		switch {
+		case e.Meta.IsDir:
+			ev.Kind = FolderCreated
+			cc.known[path] = ""
+			if exists {
+				// Folder metadata changes aren't interesting.
+				continue
+			}
+		case exists:
+			ev.Kind = Modified
+			cc.known[path] = e.Meta.Rev
+		default:
+			ev.Kind = Added
+			ev.OldPath = deletedRevs[e.Meta.Rev]
+			cc.known[path] = e.Meta.Rev
+		}
+		events = append(events, ev)
+	}
+	return events
+}
+
+// ClassifyEntries converts a single page of delta entries into ChangeEvents
+// without any knowledge of earlier pages, so changed files are reported as
+// Added rather than Modified.
+func ClassifyEntries(entries []Entry) []ChangeEvent {
+	var cc ChangeClassifier
+	return cc.Classify(&Delta{Entries: entries})
+}
+
+// CoalesceEntries collapses a batch of delta entries, possibly spanning several
+// pages, into the minimal set of entries with the same effect: only the last
+// change to each path is kept, and changes inside a folder that is later
+// deleted are dropped in favour of the folder's deletion. Relative order of the
+// remaining entries is preserved.
+//
+// Since it is unknown whether a path existed before the batch, a file created
+// and then deleted within the batch is still reported as deleted, and a
+// deletion followed by a re-creation is kept, as the deletion also removed
+// anything that was inside the path.
+func CoalesceEntries(entries []Entry) []Entry {
+	last := make(map[string]int, len(entries))
+	deleted := make(map[string]int)
+	for i, e := range entries {
+		path := NormalizePath(e.Path)
+		if e.Meta == nil {
+			for _, m := range []map[string]int{last, deleted} {
+				for p := range m {
+					if p == path || strings.HasPrefix(p, path+"/") {
+						delete(m, p)
+					}
+				}
+			}
+			deleted[path] = i
+		}
+		last[path] = i
+	}
+
+	keep := make([]bool, len(entries))
+	for _, m := range []map[string]int{last, deleted} {
+		for _, i := range m {
+			keep[i] = true
+		}
+	}
+	result := make([]Entry, 0, len(last)+len(deleted))
+	for i, e := range entries {
+		if keep[i] {
+			result = append(result, e)
+		}
+	}
+	return result
+}
@@ -0,0 +1,138 @@
+package dropbox
+
+import (
+	"reflect"
+	"testing"
+)
+
+// eventStrings formats events as "kind path", with " <- old path" for moves.
+func eventStrings(events []ChangeEvent) []string {
+	var list []string
+	for _, ev := range events {
+		s := ev.Kind.String() + " " + ev.Path
+		if ev.OldPath != "" {
+			s += " <- " + ev.OldPath
+		}
+		list = append(list, s)
+	}
+	return list
+}
+
+func TestChangeClassifier(t *testing.T) {
+	tests := []struct {
+		name  string
+		pages []*Delta
+		want  []string // events of the last page
+	}{
+		{
+			name:  "new entries",
+			pages: []*Delta{{Entries: []Entry{folderEntry("/Docs"), fileEntry("/Docs/a", "1")}}},
+			want:  []string{"folder-created /docs", "added /docs/a"},
+		},
+		{
+			name: "known file is modified",
+			pages: []*Delta{
+				{Entries: []Entry{fileEntry("/a", "1")}},
+				{Entries: []Entry{fileEntry("/A", "2")}},
+			},
+			want: []string{"modified /a"},
+		},
+		{
+			name: "reset forgets known files",
+			pages: []*Delta{
+				{Entries: []Entry{fileEntry("/a", "1")}},
+				{Reset: true, Entries: []Entry{fileEntry("/a", "2")}},
+			},
+			want: []string{"added /a"},
+		},
+		{
+			name: "known folder is not reported again",
+			pages: []*Delta{
+				{Entries: []Entry{folderEntry("/d")}},
+				{Entries: []Entry{folderEntry("/D")}},
+			},
+			want: nil,
+		},
+		{
+			name: "deleting a folder forgets its contents",
+			pages: []*Delta{
+				{Entries: []Entry{folderEntry("/d"), fileEntry("/d/a", "1")}},
+				{Entries: []Entry{deletedEntry("/d")}},
+				{Entries: []Entry{fileEntry("/d/a", "2")}},
+			},
+			want: []string{"added /d/a"},
+		},
+		{
+			name: "move within a page",
+			pages: []*Delta{
+				{Entries: []Entry{folderEntry("/d"), fileEntry("/d/a", "1")}},
+				{Entries: []Entry{deletedEntry("/d"), fileEntry("/b", "1")}},
+			},
+			want: []string{"deleted /d", "added /b <- /d/a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cc ChangeClassifier
+			var events []ChangeEvent
+			for _, d := range tt.pages {
+				events = cc.Classify(d)
+			}
+			if got := eventStrings(events); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("events = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCoalesceEntries(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []Entry
+		want    []string
+	}{
+		{
+			name:    "last change to a path wins",
+			entries: []Entry{fileEntry("/a", "1"), fileEntry("/b", "1"), fileEntry("/A", "2")},
+			want:    []string{"/b 1", "/a 2"},
+		},
+		{
+			name:    "deleted folder drops changes inside it",
+			entries: []Entry{folderEntry("/d"), fileEntry("/d/a", "1"), fileEntry("/e", "1"), deletedEntry("/D")},
+			want:    []string{"/e 1", "/d -"},
+		},
+		{
+			name:    "created then deleted is still deleted",
+			entries: []Entry{fileEntry("/a", "1"), deletedEntry("/a")},
+			want:    []string{"/a -"},
+		},
+		{
+			name:    "deleted then created keeps both",
+			entries: []Entry{fileEntry("/d/old", "1"), deletedEntry("/d"), folderEntry("/d"), fileEntry("/d/new", "1")},
+			want:    []string{"/d -", "/d dir", "/d/new 1"},
+		},
+		{
+			name:    "sibling with a common prefix is kept",
+			entries: []Entry{fileEntry("/ab", "1"), deletedEntry("/a")},
+			want:    []string{"/ab 1", "/a -"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, e := range CoalesceEntries(tt.entries) {
+				switch {
+				case e.Meta == nil:
+					got = append(got, e.Path+" -")
+				case e.Meta.IsDir:
+					got = append(got, e.Path+" dir")
+				default:
+					got = append(got, e.Path+" "+e.Meta.Rev)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CoalesceEntries = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package dropbox
+
+import "sort"
+
+// A MetadataChange is an entry present in both listings compared by
+// DiffListings, whose revision changed.
+type MetadataChange struct {
+	Old, New Metadata
+}
+
+// A ListingDiff is the difference between two listings of a dropbox, as
+// returned by DiffListings. Each slice is sorted by path.
+type ListingDiff struct {
+	Added    []Metadata       // Entries only in the new listing
+	Removed  []Metadata       // Entries only in the old listing
+	Modified []MetadataChange // Entries in both, with a different revision
+}
+
+// Empty reports whether the listings were the same.
+func (d *ListingDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Modified) == 0
+}
+
+// DiffListings compares two listings of the same dropbox, eg: the Contents of
+// a folder or the results of Walk saved at different times, to report what
+// changed between them without the delta call. Entries are matched by path,
+// ignoring case as Dropbox does, and are modified if their rev differs, or if
+// neither has a rev, their size or modification time. An entry which turned
+// from a file into a folder, or back, is modified too. Deleted entries, with
+// IsDeleted set, count as absent.
+func DiffListings(old, new []Metadata) *ListingDiff {
+	before := make(map[string]*Metadata, len(old))
+	for i := range old {
+		if !old[i].IsDeleted {
+			before[NormalizePath(old[i].Path)] = &old[i]
+		}
+	}
+
+	d := &ListingDiff{}
+	seen := make(map[string]bool, len(new))
+	for i := range new {
+		m := &new[i]
+		key := NormalizePath(m.Path)
+		if m.IsDeleted || seen[key] {
+			continue
+		}
+		seen[key] = true
+		o, ok := before[key]
+		switch {
+		case !ok:
+			d.Added = append(d.Added, *m)
+		case entryChanged(o, m):
+			d.Modified = append(d.Modified, MetadataChange{Old: *o, New: *m})
+		}
+	}
+	for key, o := range before {
+		if !seen[key] {
+			d.Removed = append(d.Removed, *o)
+		}
+	}
+
+	sort.Slice(d.Added, func(i, j int) bool { return NormalizePath(d.Added[i].Path) < NormalizePath(d.Added[j].Path) })
+	sort.Slice(d.Removed, func(i, j int) bool { return NormalizePath(d.Removed[i].Path) < NormalizePath(d.Removed[j].Path) })
+	sort.Slice(d.Modified, func(i, j int) bool {
+		return NormalizePath(d.Modified[i].New.Path) < NormalizePath(d.Modified[j].New.Path)
+	})
+	return d
+}
+
+// entryChanged reports whether an entry differs between two listings.
+func entryChanged(old, new *Metadata) bool {
+	if old.IsDir != new.IsDir {
+		return true
+	}
+	if old.Rev != "" || new.Rev != "" {
+		return old.Rev != new.Rev
+	}
+	return old.Bytes != new.Bytes || !old.Modified.Equal(new.Modified.Time)
+}
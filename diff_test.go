@@ -0,0 +1,61 @@
+package dropbox
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDiffListings(t *testing.T) {
+	t1 := Time{time.Date(2015, 1, 2, 3, 4, 5, 0, time.UTC)}
+	t2 := Time{t1.Add(time.Hour)}
+
+	old := []Metadata{
+		{Path: "/Same", Rev: "1"},
+		{Path: "/changed", Rev: "1"},
+		{Path: "/gone", Rev: "1"},
+		{Path: "/norev", Bytes: 3, Modified: t1},
+		{Path: "/touched", Bytes: 3, Modified: t1},
+		{Path: "/kind", Rev: "1"},
+		{Path: "/was-deleted", Rev: "1", IsDeleted: true},
+	}
+	cur := []Metadata{
+		{Path: "/same", Rev: "1"},
+		{Path: "/changed", Rev: "2"},
+		{Path: "/norev", Bytes: 3, Modified: t1},
+		{Path: "/touched", Bytes: 3, Modified: t2},
+		{Path: "/kind", Rev: "1", IsDir: true},
+		{Path: "/was-deleted", Rev: "2"},
+		{Path: "/b-added", Rev: "1"},
+		{Path: "/a-added", Rev: "1"},
+		{Path: "/now-deleted", Rev: "1", IsDeleted: true},
+	}
+
+	d := DiffListings(old, cur)
+	paths := func(list []Metadata) []string {
+		var p []string
+		for _, m := range list {
+			p = append(p, m.Path)
+		}
+		return p
+	}
+	if got, want := paths(d.Added), []string{"/a-added", "/b-added", "/was-deleted"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Added = %q, want %q", got, want)
+	}
+	if got, want := paths(d.Removed), []string{"/gone"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Removed = %q, want %q", got, want)
+	}
+	var modified []string
+	for _, c := range d.Modified {
+		modified = append(modified, c.Old.Path+" "+c.New.Path)
+	}
+	if want := []string{"/changed /changed", "/kind /kind", "/touched /touched"}; !reflect.DeepEqual(modified, want) {
+		t.Errorf("Modified = %q, want %q", modified, want)
+	}
+	if d.Empty() {
+		t.Error("Empty reported for different listings")
+	}
+	if d := DiffListings(old, old); !d.Empty() {
+		t.Errorf("listing differs from itself: %+v", d)
+	}
+}
@@ -0,0 +1,169 @@
+package dropbox
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// An OperationKind is the kind of an OperationEvent.
+type OperationKind int
+
+// Kinds of OperationEvent.
+const (
+	UploadStarted OperationKind = iota
+	UploadCompleted
+	UploadFailed
+	DownloadStarted
+	DownloadCompleted
+	DownloadFailed
+	EntryDeleted
+	EntryMoved
+	EntryCopied
+	EntryCreated    // A folder was created
+	OperationFailed // A Delete, Move, Copy or CreateFolder failed
+)
+
+var operationNames = []string{
+	"UploadStarted", "UploadCompleted", "UploadFailed",
+	"DownloadStarted", "DownloadCompleted", "DownloadFailed",
+	"EntryDeleted", "EntryMoved", "EntryCopied", "EntryCreated", "OperationFailed",
+}
+
+func (k OperationKind) String() string {
+	if k >= 0 && int(k) < len(operationNames) {
+		return operationNames[k]
+	}
+	return fmt.Sprintf("OperationKind(%d)", int(k))
+}
+
+// An OperationEvent describes an operation performed by a Client.
+type OperationEvent struct {
+	Kind    OperationKind
+	Time    time.Time
+	Path    string    // Path of the file, or destination of a move or copy
+	OldPath string    // Source of a move or copy, if any
+	Meta    *Metadata // Metadata returned by the operation, if it completed
+	Bytes   int64     // Bytes transferred, for completed uploads and downloads
+	Err     error     // Error of a failed operation
+}
+
+// An Observer is told about the operations of the clients of a Session whose
+// Events is an EventBus it subscribed to.
+type Observer interface {
+	Observe(e OperationEvent)
+}
+
+// ObserverFunc adapts an ordinary function to the Observer interface.
+type ObserverFunc func(e OperationEvent)
+
+// Observe calls f(e).
+func (f ObserverFunc) Observe(e OperationEvent) {
+	f(e)
+}
+
+// An EventBus delivers the OperationEvents of the clients of a Session to its
+// subscribers, eg: to keep an audit log, update a user interface or collect
+// metrics. Observers are called synchronously by the goroutine performing the
+// operation, so they must not block for long.
+type EventBus struct {
+	mu        sync.RWMutex
+	observers map[int]Observer
+	next      int
+}
+
+// Subscribe adds o to the observers of the bus, until unsubscribe is called.
+func (b *EventBus) Subscribe(o Observer) (unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.observers == nil {
+		b.observers = make(map[int]Observer)
+	}
+	id := b.next
+	b.next++
+	b.observers[id] = o
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.observers, id)
+	}
+}
+
+// Publish delivers e to all the observers of the bus.
+func (b *EventBus) Publish(e OperationEvent) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, o := range b.observers {
+		o.Observe(e)
+	}
+}
+
+// emit publishes e on the EventBus of the Session, if any.
+func (c *Client) emit(e OperationEvent) {
+	if c.Events != nil {
+		c.Events.Publish(e)
+	}
+}
+
+// emitResult publishes the outcome of a Delete, Move, Copy or CreateFolder.
+func (c *Client) emitResult(kind OperationKind, path, oldPath string, meta *Metadata, err error) {
+	if err != nil {
+		kind = OperationFailed
+	}
+	c.emit(OperationEvent{Kind: kind, Path: path, OldPath: oldPath, Meta: meta, Err: err})
+}
+
+// trackUpload publishes the start of an upload to path, and returns a function
+// publishing its outcome.
+func (c *Client) trackUpload(path string) func(meta *Metadata, n int64, err error) {
+	c.emit(OperationEvent{Kind: UploadStarted, Path: path})
+	return func(meta *Metadata, n int64, err error) {
+		if err != nil {
+			c.emit(OperationEvent{Kind: UploadFailed, Path: path, Err: err})
+		} else {
+			c.emit(OperationEvent{Kind: UploadCompleted, Path: path, Meta: meta, Bytes: n})
+		}
+	}
+}
+
+// An observedBody publishes the outcome of a download once its body has been
+// read to the end or failed. A body closed before the end is reported as
+// failed with io.ErrUnexpectedEOF.
+type observedBody struct {
+	io.ReadCloser
+	c    *Client
+	path string
+	meta *Metadata
+	n    int64
+	once sync.Once
+}
+
+func (b *observedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	if err == io.EOF {
+		b.done(nil)
+	} else if err != nil {
+		b.done(err)
+	}
+	return n, err
+}
+
+func (b *observedBody) Close() error {
+	b.done(io.ErrUnexpectedEOF)
+	return b.ReadCloser.Close()
+}
+
+func (b *observedBody) done(err error) {
+	b.once.Do(func() {
+		if err != nil {
+			b.c.emit(OperationEvent{Kind: DownloadFailed, Path: b.path, Err: err, Bytes: b.n})
+		} else {
+			b.c.emit(OperationEvent{Kind: DownloadCompleted, Path: b.path, Meta: b.meta, Bytes: b.n})
+		}
+	})
+}
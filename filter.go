@@ -0,0 +1,149 @@
+package dropbox
+
+import (
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// A SearchFilter narrows down search results on the client side, since the
+// search call only matches parts of file names. Zero fields don't filter.
+type SearchFilter struct {
+	Name          *regexp.Regexp // Matched against the name of the entry
+	Extensions    []string       // Allowed extensions, eg: ".jpg", compared case-insensitively
+	MinSize       int64          // Smallest size in bytes
+	MaxSize       int64          // Largest size in bytes, no limit if 0
+	ModifiedAfter time.Time      // Only entries modified after this time
+}
+
+// Match reports whether meta passes the filter.
+func (f *SearchFilter) Match(meta *Metadata) bool {
+	if f.Name != nil && !f.Name.MatchString(path.Base(meta.Path)) {
+		return false
+	}
+	if len(f.Extensions) > 0 && !hasExtension(meta.Path, f.Extensions) {
+		return false
+	}
+	if meta.Bytes < f.MinSize || (f.MaxSize > 0 && meta.Bytes > f.MaxSize) {
+		return false
+	}
+	if !f.ModifiedAfter.IsZero() && !meta.Modified.After(f.ModifiedAfter) {
+		return false
+	}
+	return true
+}
+
+func hasExtension(p string, extensions []string) bool {
+	ext := path.Ext(p)
+	for _, e := range extensions {
+		if !strings.HasPrefix(e, ".") {
+			e = "." + e
+		}
+		if strings.EqualFold(ext, e) {
+			return true
+		}
+	}
+	return false
+}
+
+// SearchFiltered calls Search, and returns only the results passing filter.
+// As the filter is applied after fileLimit, fewer results than the limit may
+// be returned even if more exist.
+func (c *Client) SearchFiltered(path, query string, fileLimit int, deleted bool, filter *SearchFilter) ([]*Metadata, error) {
+	results, err := c.Search(path, query, fileLimit, deleted)
+	if err != nil || filter == nil {
+		return results, err
+	}
+	matches := results[:0]
+	for _, m := range results {
+		if filter.Match(m) {
+			matches = append(matches, m)
+		}
+	}
+	return matches, nil
+}
+
+// A SortKey is the order of entries in a listing.
+type SortKey int
+
+// Orders of a listing.
+const (
+	SortNone       SortKey = iota // The order returned by the server
+	SortByName                    // By name, case-insensitively
+	SortBySize                    // By size in bytes
+	SortByModified                // By modification time
+)
+
+// ListOptions sort and filter a folder listing on the client side. Extensions
+// and MinSize only filter files, folders are kept unless FilesOnly is set.
+type ListOptions struct {
+	SortBy    SortKey
+	Reverse   bool // Sort in descending order
+	DirsFirst bool // Put folders before files, whatever the order
+
+	DirsOnly   bool
+	FilesOnly  bool
+	Extensions []string // Allowed file extensions, eg: ".jpg", compared case-insensitively
+	MinSize    int64    // Smallest file size in bytes
+}
+
+// Apply filters and sorts entries in place, and returns the entries kept.
+func (o *ListOptions) Apply(entries []Metadata) []Metadata {
+	kept := entries[:0]
+	for _, m := range entries {
+		if o.keep(&m) {
+			kept = append(kept, m)
+		}
+	}
+	if o.SortBy == SortNone && !o.DirsFirst {
+		return kept
+	}
+
+	sort.SliceStable(kept, func(i, j int) bool {
+		a, b := &kept[i], &kept[j]
+		if o.DirsFirst && a.IsDir != b.IsDir {
+			return a.IsDir
+		}
+		if o.Reverse {
+			a, b = b, a
+		}
+		switch o.SortBy {
+		case SortByName:
+			return strings.ToLower(path.Base(a.Path)) < strings.ToLower(path.Base(b.Path))
+		case SortBySize:
+			return a.Bytes < b.Bytes
+		case SortByModified:
+			return a.Modified.Before(b.Modified.Time)
+		}
+		return false
+	})
+	return kept
+}
+
+func (o *ListOptions) keep(m *Metadata) bool {
+	if m.IsDir {
+		return !o.FilesOnly
+	}
+	if o.DirsOnly {
+		return false
+	}
+	if len(o.Extensions) > 0 && !hasExtension(m.Path, o.Extensions) {
+		return false
+	}
+	return m.Bytes >= o.MinSize
+}
+
+// ReadDir returns the entries of the folder at path, of any size, sorted and
+// filtered by opts, which may be nil.
+func (c *Client) ReadDir(path string, opts *ListOptions) ([]Metadata, error) {
+	meta, err := c.ListLargeFolder(path)
+	if err != nil {
+		return nil, err
+	}
+	if opts == nil {
+		return meta.Contents, nil
+	}
+	return opts.Apply(meta.Contents), nil
+}
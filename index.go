@@ -0,0 +1,303 @@
+package dropbox
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// A KV is a minimal key/value store, used to persist a MetadataIndex. It can be
+// implemented on top of any embedded database.
+type KV interface {
+	// Get returns the value of key, and whether it exists.
+	Get(key string) ([]byte, bool, error)
+	Put(key string, value []byte) error
+	Delete(key string) error
+	// Scan calls fn for each key with the given prefix, in sorted order.
+	// The store is not modified during the scan.
+	Scan(prefix string, fn func(key string, value []byte) error) error
+}
+
+// A MemoryKV is a KV held in memory, which can be saved to and loaded from a
+// file. It is safe for concurrent use. The zero value is an empty store.
+type MemoryKV struct {
+	mu   sync.RWMutex
+	data map[string][]byte
+}
+
+// Get returns the value of key, and whether it exists.
+func (m *MemoryKV) Get(key string) ([]byte, bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	v, ok := m.data[key]
+	return v, ok, nil
+}
+
+// Put sets the value of key.
+func (m *MemoryKV) Put(key string, value []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.data == nil {
+		m.data = make(map[string][]byte)
+	}
+	m.data[key] = value
+	return nil
+}
+
+// Delete removes key.
+func (m *MemoryKV) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.data, key)
+	return nil
+}
+
+// Scan calls fn for each key with the given prefix, in sorted order.
+func (m *MemoryKV) Scan(prefix string, fn func(key string, value []byte) error) error {
+	m.mu.RLock()
+	keys := make([]string, 0)
+	for k := range m.data {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	values := make([][]byte, len(keys))
+	sort.Strings(keys)
+	for i, k := range keys {
+		values[i] = m.data[k]
+	}
+	m.mu.RUnlock()
+
+	for i, k := range keys {
+		if err := fn(k, values[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Save writes the contents of the store to the named file.
+func (m *MemoryKV) Save(filename string) error {
+	m.mu.RLock()
+	data, err := json.Marshal(m.data)
+	m.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filename, data, 0600)
+}
+
+// Load replaces the contents of the store with those of the named file. A
+// missing file results in an empty store.
+func (m *MemoryKV) Load(filename string) error {
+	content := make(map[string][]byte)
+	data, err := ioutil.ReadFile(filename)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &content); err != nil {
+			return err
+		}
+	}
+
+	m.mu.Lock()
+	m.data = content
+	m.mu.Unlock()
+	return nil
+}
+
+// Keys used by a MetadataIndex in its KV.
+const (
+	indexCursorKey = "cursor"
+	indexMetaKey   = "meta:"
+)
+
+// ErrStopWalk may be returned by a WalkFunc to stop a walk early without
+// reporting an error.
+var ErrStopWalk = errors.New("stop walk")
+
+// A WalkFunc is called for each file and folder visited by a walk.
+type WalkFunc func(meta *Metadata) error
+
+// A MetadataIndex is a local mirror of the metadata in a dropbox, kept up to
+// date by consuming the delta call. Once synced, Stat, ReadDir and Walk are
+// answered from the index without any API calls. Paths missing from the index,
+// or queries made before the first Sync, fall back to the API. It is safe for
+// concurrent use.
+type MetadataIndex struct {
+	client *Client
+	kv     KV
+	mu     sync.Mutex // serializes Sync
+}
+
+// NewMetadataIndex creates an index of the client's root, stored in kv. An
+// index saved by a previous run resumes from its last cursor.
+func NewMetadataIndex(c *Client, kv KV) *MetadataIndex {
+	return &MetadataIndex{client: c, kv: kv}
+}
+
+func indexKey(p string) string {
+	return indexMetaKey + NormalizePath(p)
+}
+
+// Synced reports whether the index has completed at least one Sync.
+func (ix *MetadataIndex) Synced() bool {
+	_, ok, err := ix.kv.Get(indexCursorKey)
+	return ok && err == nil
+}
+
+// Sync brings the index up to date by applying all changes since the last
+// Sync. The first Sync downloads the metadata of the entire dropbox.
+func (ix *MetadataIndex) Sync() error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	cursor, _, err := ix.kv.Get(indexCursorKey)
+	if err != nil {
+		return err
+	}
+	for {
+		delta, err := ix.client.Delta(string(cursor))
+		if err != nil {
+			return err
+		}
+		if delta.Reset {
+			if err := ix.deleteTree("/"); err != nil {
+				return err
+			}
+		}
+		for _, e := range delta.Entries {
+			if err := ix.apply(e); err != nil {
+				return err
+			}
+		}
+		cursor = []byte(delta.Cursor)
+		if err := ix.kv.Put(indexCursorKey, cursor); err != nil {
+			return err
+		}
+		if !delta.HasMore {
+			return nil
+		}
+	}
+}
+
+func (ix *MetadataIndex) apply(e Entry) error {
+	if e.Meta == nil {
+		return ix.deleteTree(e.Path)
+	}
+	data, err := json.Marshal(e.Meta)
+	if err != nil {
+		return err
+	}
+	return ix.kv.Put(indexKey(e.Path), data)
+}
+
+// deleteTree removes the entry at p and everything below it.
+func (ix *MetadataIndex) deleteTree(p string) error {
+	key := indexKey(p)
+	var keys []string
+	if err := ix.kv.Scan(strings.TrimSuffix(key, "/"), func(k string, _ []byte) error {
+		if k == key || strings.HasPrefix(k, strings.TrimSuffix(key, "/")+"/") {
+			keys = append(keys, k)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	for _, k := range keys {
+		if err := ix.kv.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ix *MetadataIndex) lookup(p string) (*Metadata, bool, error) {
+	data, ok, err := ix.kv.Get(indexKey(p))
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	var meta Metadata
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, false, err
+	}
+	return &meta, true, nil
+}
+
+// Stat returns the metadata of the file or folder at path.
+func (ix *MetadataIndex) Stat(p string) (*Metadata, error) {
+	if path.Clean("/"+p) != "/" {
+		meta, ok, err := ix.lookup(p)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return meta, nil
+		}
+	}
+	meta, _, err := ix.client.Metadata(p, 0, "", false, false, "")
+	return meta, err
+}
+
+// ReadDir returns the metadata of the entries in the folder at path.
+func (ix *MetadataIndex) ReadDir(p string) ([]Metadata, error) {
+	dir := path.Clean("/" + p)
+	if ix.Synced() {
+		isDir := dir == "/"
+		if !isDir {
+			meta, ok, err := ix.lookup(dir)
+			if err != nil {
+				return nil, err
+			}
+			isDir = ok && meta.IsDir
+		}
+		if isDir {
+			var contents []Metadata
+			err := ix.walkIndex(dir, func(meta *Metadata) error {
+				if EqualPath(path.Dir(meta.Path), dir) {
+					contents = append(contents, *meta)
+				}
+				return nil
+			})
+			return contents, err
+		}
+	}
+
+	meta, _, err := ix.client.Metadata(dir, 0, "", true, false, "")
+	if err != nil {
+		return nil, err
+	}
+	return meta.Contents, nil
+}
+
+// Walk calls fn for every file and folder below the folder at path, ordered by
+// lower-cased path. If the index hasn't been synced, it first calls Sync.
+func (ix *MetadataIndex) Walk(p string, fn WalkFunc) error {
+	if !ix.Synced() {
+		if err := ix.Sync(); err != nil {
+			return err
+		}
+	}
+	err := ix.walkIndex(path.Clean("/"+p), fn)
+	if err == ErrStopWalk {
+		return nil
+	}
+	return err
+}
+
+func (ix *MetadataIndex) walkIndex(dir string, fn WalkFunc) error {
+	prefix := strings.TrimSuffix(indexKey(dir), "/") + "/"
+	return ix.kv.Scan(prefix, func(_ string, data []byte) error {
+		var meta Metadata
+		if err := json.Unmarshal(data, &meta); err != nil {
+			return err
+		}
+		return fn(&meta)
+	})
+}
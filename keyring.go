@@ -0,0 +1,35 @@
+package dropbox
+
+import "errors"
+
+// ErrKeyringUnavailable is returned by a SystemKeyring on systems where it is
+// not supported, or when the system's secret storage can't be reached.
+var ErrKeyringUnavailable = errors.New("system keyring unavailable")
+
+// A SystemKeyring is the Keyring of the operating system: the login keychain
+// on macOS, the Credential Manager on Windows, and the Secret Service API
+// (eg: GNOME Keyring or KWallet) through the secret-tool command elsewhere.
+// On other systems all its methods fail with ErrKeyringUnavailable.
+type SystemKeyring struct{}
+
+// Get returns the secret stored for service and user.
+func (SystemKeyring) Get(service, user string) (string, error) {
+	return keyringGet(service, user)
+}
+
+// Set stores secret for service and user, replacing any previous one.
+func (SystemKeyring) Set(service, user, secret string) error {
+	return keyringSet(service, user, secret)
+}
+
+// Delete removes the secret stored for service and user, if any.
+func (SystemKeyring) Delete(service, user string) error {
+	return keyringDelete(service, user)
+}
+
+// NewSystemTokenStore returns a TokenStore keeping credentials in the
+// SystemKeyring, under the given service name, eg: the name of the
+// application.
+func NewSystemTokenStore(service string) *KeyringTokenStore {
+	return &KeyringTokenStore{Service: service, Keyring: SystemKeyring{}}
+}
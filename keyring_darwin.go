@@ -0,0 +1,64 @@
+//go:build darwin
+
+package dropbox
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// The keychain is used through the security command. Secrets are passed to it
+// on its standard input, in its interactive mode, so they don't appear in the
+// process list.
+
+// errItemNotFound is the exit status of security when no item matches.
+const errItemNotFound = 44
+
+func keyringGet(service, user string) (string, error) {
+	out, err := exec.Command("security", "find-generic-password", "-s", service, "-a", user, "-w").Output()
+	if err != nil {
+		var exit *exec.ExitError
+		if errors.As(err, &exit) && exit.ExitCode() == errItemNotFound {
+			return "", ErrTokenNotFound
+		}
+		return "", keychainError(err)
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
+
+func keyringSet(service, user, secret string) error {
+	command := fmt.Sprintf("add-generic-password -U -s %s -a %s -X %s\n",
+		strconv.Quote(service), strconv.Quote(user), hex.EncodeToString([]byte(secret)))
+	cmd := exec.Command("security", "-i")
+	cmd.Stdin = strings.NewReader(command)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return keychainError(err)
+	}
+	if stderr.Len() > 0 {
+		return fmt.Errorf("keychain: %s", strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+func keyringDelete(service, user string) error {
+	err := exec.Command("security", "delete-generic-password", "-s", service, "-a", user).Run()
+	var exit *exec.ExitError
+	if errors.As(err, &exit) && exit.ExitCode() == errItemNotFound {
+		return nil
+	}
+	return keychainError(err)
+}
+
+func keychainError(err error) error {
+	if errors.Is(err, exec.ErrNotFound) {
+		return ErrKeyringUnavailable
+	}
+	return err
+}
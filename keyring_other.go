@@ -0,0 +1,15 @@
+//go:build !darwin && !windows && !linux && !freebsd && !openbsd && !netbsd && !dragonfly
+
+package dropbox
+
+func keyringGet(service, user string) (string, error) {
+	return "", ErrKeyringUnavailable
+}
+
+func keyringSet(service, user, secret string) error {
+	return ErrKeyringUnavailable
+}
+
+func keyringDelete(service, user string) error {
+	return ErrKeyringUnavailable
+}
@@ -0,0 +1,49 @@
+//go:build linux || freebsd || openbsd || netbsd || dragonfly
+
+package dropbox
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+)
+
+// The Secret Service is used through the secret-tool command of libsecret,
+// which reads secrets from its standard input, so they don't appear in the
+// process list. Items are identified by service and user attributes.
+
+func keyringGet(service, user string) (string, error) {
+	out, err := exec.Command("secret-tool", "lookup", "service", service, "user", user).Output()
+	if err != nil {
+		var exit *exec.ExitError
+		if errors.As(err, &exit) && len(exit.Stderr) == 0 {
+			// secret-tool fails silently when nothing matches.
+			return "", ErrTokenNotFound
+		}
+		return "", secretToolError(err)
+	}
+	return string(out), nil
+}
+
+func keyringSet(service, user, secret string) error {
+	cmd := exec.Command("secret-tool", "store", "--label", service+" ("+user+")", "service", service, "user", user)
+	cmd.Stdin = strings.NewReader(secret)
+	return secretToolError(cmd.Run())
+}
+
+func keyringDelete(service, user string) error {
+	_, err := exec.Command("secret-tool", "clear", "service", service, "user", user).Output()
+	var exit *exec.ExitError
+	if errors.As(err, &exit) && len(exit.Stderr) == 0 {
+		// Nothing matched.
+		return nil
+	}
+	return secretToolError(err)
+}
+
+func secretToolError(err error) error {
+	if errors.Is(err, exec.ErrNotFound) {
+		return ErrKeyringUnavailable
+	}
+	return err
+}
@@ -0,0 +1,70 @@
+package dropbox
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// A ManifestEntry describes a file of a Manifest.
+type ManifestEntry struct {
+	Path        string `json:"path"`
+	Bytes       int64  `json:"bytes"`
+	Rev         string `json:"rev"`
+	ContentHash string `json:"content_hash"` // As computed by ContentHash
+}
+
+// A Manifest lists the files below a folder with their checksums, so copies
+// of them can be verified, eg: after a migration or by an external audit.
+// Entries are sorted by path.
+type Manifest []ManifestEntry
+
+// Manifest lists the files below the folder at path, with their content hash,
+// which Dropbox computes, so no file is downloaded.
+func (c *Client) Manifest(path string) (Manifest, error) {
+	entries, _, err := c.ListFolderAll(path, &ListFolderOptions{Recursive: true})
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	for i := range entries {
+		e := &entries[i]
+		if e.Tag != "file" {
+			continue
+		}
+		m = append(m, ManifestEntry{Path: e.PathDisplay, Bytes: e.Size, Rev: e.Rev, ContentHash: e.ContentHash})
+	}
+	sort.Slice(m, func(i, j int) bool { return NormalizePath(m[i].Path) < NormalizePath(m[j].Path) })
+	return m, nil
+}
+
+// WriteJSON writes the manifest to w as a JSON array of entries.
+func (m Manifest) WriteJSON(w io.Writer) error {
+	if m == nil {
+		m = Manifest{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(m)
+}
+
+// manifestHeader is the first record of a manifest written as CSV.
+var manifestHeader = []string{"path", "bytes", "rev", "content_hash"}
+
+// WriteCSV writes the manifest to w as CSV, with a header record naming the
+// columns as in the JSON form.
+func (m Manifest) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(manifestHeader); err != nil {
+		return err
+	}
+	for _, e := range m {
+		if err := cw.Write([]string{e.Path, strconv.FormatInt(e.Bytes, 10), e.Rev, e.ContentHash}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,99 @@
+package dropbox
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxNameLength is the maximum length in bytes of a file or folder name.
+const MaxNameLength = 255
+
+// A PathError reports why a path can't be used in a dropbox.
+type PathError struct {
+	Path   string
+	Reason string
+}
+
+func (pe *PathError) Error() string {
+	return fmt.Sprintf("invalid path %q: %s", pe.Path, pe.Reason)
+}
+
+// reservedNames are names Dropbox won't store, compared case-insensitively.
+var reservedNames = map[string]bool{
+	"desktop.ini":   true,
+	"thumbs.db":     true,
+	".ds_store":     true,
+	"icon\r":        true,
+	".dropbox":      true,
+	".dropbox.attr": true,
+}
+
+// ValidatePath checks that p is a path Dropbox will accept, so bad names can
+// be reported before a request is attempted. It rejects invalid UTF-8, control
+// characters and backslashes, names which are "." or "..", end in a space or a
+// dot, are longer than MaxNameLength bytes, or are reserved by Dropbox, such
+// as "desktop.ini" or ".ds_store". Ids ("id:...") and revisions ("rev:...")
+// are accepted as they are.
+func ValidatePath(p string) error {
+	if strings.HasPrefix(p, "id:") || strings.HasPrefix(p, "rev:") {
+		return nil
+	}
+	if !utf8.ValidString(p) {
+		return &PathError{p, "not valid UTF-8"}
+	}
+	for _, name := range strings.Split(p, "/") {
+		if name == "" {
+			continue
+		}
+		if err := validateName(name); err != "" {
+			return &PathError{p, fmt.Sprintf("name %q %s", name, err)}
+		}
+	}
+	return nil
+}
+
+// validateName returns why name is invalid, or the empty string if it isn't.
+func validateName(name string) string {
+	switch {
+	case name == "." || name == "..":
+		return "is not allowed"
+	case len(name) > MaxNameLength:
+		return fmt.Sprintf("is longer than %d bytes", MaxNameLength)
+	case strings.HasSuffix(name, " "):
+		return "ends with a space"
+	case strings.HasSuffix(name, "."):
+		return "ends with a dot"
+	case reservedNames[strings.ToLower(name)]:
+		return "is reserved by Dropbox"
+	}
+	for _, r := range name {
+		if r < 0x20 || r == 0x7f {
+			return fmt.Sprintf("contains the control character %U", r)
+		}
+		if r == '\\' {
+			return "contains a backslash"
+		}
+	}
+	return ""
+}
+
+// NormalizePath returns the form of p used to compare paths: cleaned, rooted
+// at "/" and lower-cased. Dropbox paths are case-insensitive but preserve the
+// case they were created with, so paths differing only by case must be
+// treated as the same file.
+func NormalizePath(p string) string {
+	return strings.ToLower(path.Clean("/" + p))
+}
+
+// EqualPath reports whether a and b refer to the same file in a dropbox.
+func EqualPath(a, b string) bool {
+	return NormalizePath(a) == NormalizePath(b)
+}
+
+// HasPrefixPath reports whether p is the folder prefix or inside it.
+func HasPrefixPath(p, prefix string) bool {
+	p, prefix = NormalizePath(p), NormalizePath(prefix)
+	return prefix == "/" || p == prefix || strings.HasPrefix(p, prefix+"/")
+}
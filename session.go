@@ -1,11 +1,16 @@
 package dropbox
 
 import (
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"github.com/garyburd/go-oauth/oauth"
 	"net/http"
 	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
 )
 
 // Constants to build URLs
@@ -17,10 +22,12 @@ const (
 	APIHost     = "api.dropbox.com"
 	WWWHost     = "www.dropbox.com"
 	ContentHost = "api-content.dropbox.com"
+	NotifyHost  = "api-notify.dropbox.com"
 
 	APIPrefix     = Scheme + APIHost + Prefix
 	WWWPrefix     = Scheme + WWWHost + Prefix
 	ContentPrefix = Scheme + ContentHost + Prefix
+	NotifyPrefix  = Scheme + NotifyHost + Prefix
 )
 
 // Authorization URLs
@@ -43,6 +50,17 @@ func (ae *AuthorizationError) Error() string {
 	return fmt.Sprintf("Authorization Error (%s)", ae.Context)
 }
 
+// Unwrap returns the cause of the error.
+func (ae *AuthorizationError) Unwrap() error {
+	return ae.Cause
+}
+
+// Is reports whether target is ErrUnauthorized, so all authorization errors
+// match it.
+func (ae *AuthorizationError) Is(target error) bool {
+	return target == ErrUnauthorized
+}
+
 // Credentials represents the a set of authentication credentials in the
 // Dropbox OAuth v1 API. They are used to sign and authenticate all communication
 // with the Dropbox servers.
@@ -76,13 +94,130 @@ type Session struct {
 	OauthClient               oauth.Client
 	HTTPClient                *http.Client
 	Locale                    string
+
+	// OAuth2Token, if set, is sent as a bearer token instead of signing
+	// requests with the OAuth v1 AccessToken.
+	OAuth2Token string
+
+	// QuerySigning restores the old behaviour of placing OAuth v1 signatures
+	// in the query string or form body instead of the Authorization header.
+	// Signatures in the URL may end up in proxy and server logs. Version 2
+	// calls are always authorized in the header.
+	QuerySigning bool
+
+	// TokenStore, if set, is used to persist the access token. Tokens
+	// acquired by GetAccessToken or GetAccessTokenCallback are saved to it,
+	// and LoadAccessToken reads them back. Account is the key used in the
+	// store, if it is empty the app key is used instead.
+	TokenStore TokenStore
+	Account    string
+
+	// AccountInfoTTL is how long Client.CachedAccountInfo reuses a
+	// response before fetching it again. If zero, DefaultAccountInfoTTL
+	// is used.
+	AccountInfoTTL time.Duration
+
+	// BaseURLs overrides the servers requests are sent to. Use
+	// SetBaseURLs to change it, so the OAuth endpoints are updated too.
+	BaseURLs BaseURLs
+
+	// JobPoller controls how asynchronous batch jobs are waited for. If
+	// nil, DefaultJobPoller is used.
+	JobPoller *JobPoller
+
+	// TLSConfig, if set, is used for the connections of the Session's
+	// default HTTP client. It is ignored if HTTPClient is set.
+	TLSConfig *tls.Config
+
+	// PinnedKeys, if set, restricts the default HTTP client to servers
+	// whose verified certificate chain contains a public key with one of
+	// the given hashes, as computed by SPKIHash. If verification is
+	// skipped, only the server's own certificate is checked. It is ignored
+	// if HTTPClient is set.
+	PinnedKeys []string
+
+	// Proxy, if set, selects the proxy used by the default HTTP client.
+	// Otherwise the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment
+	// variables are used. It is ignored if HTTPClient is set.
+	Proxy func(*http.Request) (*url.URL, error)
+
+	// CheckRedirect is the redirect policy of the default HTTP client. If
+	// nil, SafeRedirect is used. It is ignored if HTTPClient is set.
+	CheckRedirect func(req *http.Request, via []*http.Request) error
+
+	// Timeout, if not zero, limits the time taken by each request of the
+	// default HTTP client, including reading the response body, so it must
+	// allow for the largest transfers and for longpolls. It is ignored if
+	// HTTPClient is set.
+	Timeout time.Duration
+
+	// Connection settings of the default HTTP client, which are ignored if
+	// HTTPClient is set. Zero values keep the defaults of
+	// http.DefaultTransport. MaxIdleConnsPerHost is the number of idle
+	// connections kept for reuse with each server, IdleConnTimeout how long
+	// they are kept, and DialTimeout limits the time taken to connect.
+	// DisableHTTP2 makes the client use HTTP/1.1 only.
+	MaxIdleConnsPerHost int
+	IdleConnTimeout     time.Duration
+	DialTimeout         time.Duration
+	DisableHTTP2        bool
+
+	// Retry, if set, is the RetryPolicy of the Session's clients. If nil,
+	// requests are not retried.
+	Retry *RetryPolicy
+
+	// Reauthorize, if set, is the ReauthorizeFunc of the Session's clients.
+	Reauthorize ReauthorizeFunc
+
+	// Breaker, if set, makes requests fail fast with ErrCircuitOpen while
+	// Dropbox keeps failing.
+	Breaker *CircuitBreaker
+
+	// Limiter, if set, is waited on before every request. ContentLimiter,
+	// if set, is used instead for uploads and downloads, which Dropbox
+	// limits separately from metadata calls.
+	Limiter        Limiter
+	ContentLimiter Limiter
+
+	// Metrics, if set, counts the calls made by the Session's clients.
+	Metrics *Metrics
+
+	// Events, if set, is told about the uploads, downloads and file
+	// operations of the Session's clients.
+	Events *EventBus
+
+	// Audit, if set, records every call of the Session's clients which may
+	// change a dropbox.
+	Audit *AuditLog
+
+	// JSONMode controls how unknown fields in API responses are handled.
+	// By default they are ignored; JSONStrict makes them an error and
+	// JSONLogUnknown logs them with the standard logger.
+	JSONMode JSONMode
+
+	// ChunkTuner, if set, chooses the chunk size of chunked uploads which
+	// don't specify one. Otherwise DefaultChunkSize is used.
+	ChunkTuner *ChunkTuner
+
+	accountMu      sync.Mutex
+	accountInfo    *AccountInfo
+	accountFetched time.Time
+
+	defaultClientOnce sync.Once
+	defaultClient     *http.Client
 }
 
+// client returns the HTTPClient of the Session, or if there is none a default
+// client built from the Session's transport settings, which must not be
+// changed after the first request.
 func (s *Session) client() *http.Client {
 	if s.HTTPClient != nil {
 		return s.HTTPClient
 	}
-	return http.DefaultClient
+	s.defaultClientOnce.Do(func() {
+		s.defaultClient = s.newDefaultClient()
+	})
+	return s.defaultClient
 }
 
 // NewSession creates a new Session object using the given data.
@@ -104,6 +239,79 @@ func NewSession(appKey, appSecret string, httpClient *http.Client, accessToken *
 	}
 }
 
+// Environment variables read by NewSessionFromEnv.
+const (
+	EnvAppKey       = "DROPBOX_APP_KEY"
+	EnvAppSecret    = "DROPBOX_APP_SECRET"
+	EnvAccessToken  = "DROPBOX_ACCESS_TOKEN"
+	EnvAccessSecret = "DROPBOX_ACCESS_SECRET"
+	EnvLocale       = "DROPBOX_LOCALE"
+)
+
+// NewSessionFromEnv creates a Session from the environment. The app key and
+// secret are read from DROPBOX_APP_KEY and DROPBOX_APP_SECRET, which must be
+// set. If DROPBOX_ACCESS_TOKEN is set, the Session is authorized with it: as
+// an OAuth v1 access token if DROPBOX_ACCESS_SECRET is set too, otherwise as
+// an OAuth2 bearer token. DROPBOX_LOCALE, if set, is used as the Locale.
+func NewSessionFromEnv() (*Session, error) {
+	key, secret := os.Getenv(EnvAppKey), os.Getenv(EnvAppSecret)
+	if key == "" || secret == "" {
+		return nil, fmt.Errorf("%s and %s must be set", EnvAppKey, EnvAppSecret)
+	}
+	s := NewSession(key, secret, nil, nil)
+	if token := os.Getenv(EnvAccessToken); token != "" {
+		if tokenSecret := os.Getenv(EnvAccessSecret); tokenSecret != "" {
+			s.AccessToken = &Credentials{Token: token, Secret: tokenSecret}
+		} else {
+			s.OAuth2Token = token
+		}
+	}
+	s.Locale = os.Getenv(EnvLocale)
+	return s, nil
+}
+
+// BaseURLs replace the scheme and host of the Dropbox servers, eg: with
+// "http://localhost:8080", so tests can use local fakes and deployments can
+// route requests through gateways. Empty fields leave the default servers in
+// place. Both the version 1 and version 2 hosts are replaced.
+type BaseURLs struct {
+	API     string // Replaces https://api.dropbox.com and https://api.dropboxapi.com
+	Content string // Replaces https://api-content.dropbox.com and https://content.dropboxapi.com
+	Notify  string // Replaces https://api-notify.dropbox.com and https://notify.dropboxapi.com
+	WWW     string // Replaces https://www.dropbox.com
+}
+
+// rewrite returns urlStr with its default server replaced by the matching
+// override, if any.
+func (b *BaseURLs) rewrite(urlStr string) string {
+	for _, r := range []struct{ base, host string }{
+		{b.API, APIHost},
+		{b.API, APIv2Host},
+		{b.Content, ContentHost},
+		{b.Content, ContentV2Host},
+		{b.Notify, NotifyHost},
+		{b.Notify, NotifyV2Host},
+		{b.WWW, WWWHost},
+	} {
+		if r.base == "" {
+			continue
+		}
+		if rest := strings.TrimPrefix(urlStr, Scheme+r.host); rest != urlStr && (rest == "" || rest[0] == '/') {
+			return strings.TrimSuffix(r.base, "/") + rest
+		}
+	}
+	return urlStr
+}
+
+// SetBaseURLs changes the servers the Session sends requests to, including
+// those used for authorization.
+func (s *Session) SetBaseURLs(b BaseURLs) {
+	s.BaseURLs = b
+	s.OauthClient.TemporaryCredentialRequestURI = b.rewrite(RequestURI)
+	s.OauthClient.ResourceOwnerAuthorizationURI = b.rewrite(AuthorizationURI)
+	s.OauthClient.TokenRequestURI = b.rewrite(AccessURI)
+}
+
 // Reset the session so that new credentials must be retrieved
 // from the Dropbox API server.
 func (s *Session) Reset() {
@@ -152,13 +360,31 @@ func (s *Session) GetAuthorizeURL(callback string) (string, error) {
 	return s.OauthClient.AuthorizationURL(s.RequestToken.oauth(), params), nil
 }
 
+// GetAuthorizeURLWithState is like GetAuthorizeURL, but attaches an opaque
+// state value to the callback URL as the "state" query parameter. Web
+// applications should tie the state to the user's browser (eg: in a cookie)
+// and verify it in the callback to protect against login CSRF.
+func (s *Session) GetAuthorizeURLWithState(callback, state string) (string, error) {
+	if callback == "" {
+		return "", errors.New("state requires a callback url")
+	}
+	u, err := url.Parse(callback)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	q.Set("state", state)
+	u.RawQuery = q.Encode()
+	return s.GetAuthorizeURL(u.String())
+}
+
 // Authorized returns true if the session believes that it has been authorized.
 // This simply checks to see if there are access credentials in the Session.
 //
 // NOTE: The user can still de-authorize a set of access credentials, so this method
 // can result in a false positive.
 func (s *Session) Authorized() bool {
-	return s.AccessToken != nil
+	return s.AccessToken != nil || s.OAuth2Token != ""
 }
 
 // GetAccessTokenCallback is to be used by a web-application to handle the return of the
@@ -172,7 +398,7 @@ func (s *Session) GetAccessTokenCallback(requestToken *Credentials, verifier str
 		return err
 	}
 	s.AccessToken = fromOauth(cred)
-	return nil
+	return s.saveAccessToken()
 }
 
 // GetAccessToken requests an access token from the server assuming that the request token
@@ -195,10 +421,52 @@ func (s *Session) GetAccessToken() error {
 		return err
 	}
 	s.AccessToken = fromOauth(cred)
-	return nil
+	return s.saveAccessToken()
+}
+
+func (s *Session) account() string {
+	if s.Account != "" {
+		return s.Account
+	}
+	return s.OauthClient.Credentials.Token
 }
 
-// SignParam adds signing parameters to the params hash given using the Session's
+// LoadAccessToken reads the access token for the Session's account from its
+// TokenStore. It returns true if a token was found and is now in use. If the
+// Session has no TokenStore this method is a no-op.
+func (s *Session) LoadAccessToken() (bool, error) {
+	if s.TokenStore == nil {
+		return false, nil
+	}
+	cred, err := s.TokenStore.Get(s.account())
+	if err == ErrTokenNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	s.AccessToken = cred
+	return true, nil
+}
+
+// ForgetAccessToken resets the session and removes its access token from
+// the TokenStore, if any.
+func (s *Session) ForgetAccessToken() error {
+	s.Reset()
+	if s.TokenStore == nil {
+		return nil
+	}
+	return s.TokenStore.Delete(s.account())
+}
+
+func (s *Session) saveAccessToken() error {
+	if s.TokenStore == nil || s.AccessToken == nil {
+		return nil
+	}
+	return s.TokenStore.Put(s.account(), s.AccessToken)
+}
+
+// signParam adds signing parameters to the params hash given using the Session's
 // access token. If the session is not authorized (no access token) this method
 // returns an error.
 func (s *Session) signParam(method, url string, params url.Values) error {
@@ -209,3 +477,22 @@ func (s *Session) signParam(method, url string, params url.Values) error {
 	s.OauthClient.SignParam(s.AccessToken.oauth(), method, url, params)
 	return nil
 }
+
+// authorize sets the Authorization header of the request using either the
+// Session's OAuth2 bearer token or an OAuth v1 signature over the given params,
+// which must be the request's query or form parameters. If the session is not
+// authorized this method returns an error.
+func (s *Session) authorize(req *http.Request, params url.Values) error {
+	if s.OAuth2Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.OAuth2Token)
+		return nil
+	}
+	if !s.Authorized() {
+		return errors.New("session not authorized")
+	}
+
+	u := *req.URL
+	u.RawQuery = ""
+	req.Header.Set("Authorization", s.OauthClient.AuthorizationHeader(s.AccessToken.oauth(), req.Method, &u, params))
+	return nil
+}
@@ -0,0 +1,226 @@
+package dropbox
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// OpenBrowser opens url in the user's web browser. It is used by
+// AuthorizeInteractive, and may be replaced, eg: to print the URL instead.
+var OpenBrowser = openBrowser
+
+func openBrowser(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait()
+	return nil
+}
+
+// interactivePage is shown in the browser once authorization completes.
+const interactivePage = `<!DOCTYPE html>
+<html><head><title>Dropbox authorization</title></head>
+<body><p>%s You can close this window.</p></body></html>
+`
+
+// AuthorizeInteractive authorizes the Session for command-line programs. It
+// serves a callback on a random port of the loopback interface, opens the
+// Dropbox authorization page in the user's browser with OpenBrowser, and waits
+// until the user approves or denies access, or ctx is done. The access token
+// is then acquired, and saved to the TokenStore if there is one. If the
+// browser can't be opened, the URL is printed to standard error for the user
+// to visit.
+func (s *Session) AuthorizeInteractive(ctx context.Context) error {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	state, err := newState()
+	if err != nil {
+		return &AuthorizationError{"state", err}
+	}
+	s.Reset()
+	callback := fmt.Sprintf("http://%s/callback", l.Addr())
+	authURL, err := s.GetAuthorizeURLWithState(callback, state)
+	if err != nil {
+		return &AuthorizationError{"request token", err}
+	}
+
+	result := make(chan error, 1)
+	var once sync.Once
+	mux := http.NewServeMux()
+	mux.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
+		// Requests which don't carry the state weren't sent by Dropbox
+		// for this authorization, and are ignored.
+		if subtle.ConstantTimeCompare([]byte(r.FormValue("state")), []byte(state)) != 1 {
+			http.Error(w, "state mismatch", http.StatusForbidden)
+			return
+		}
+		handled := false
+		var err error
+		once.Do(func() {
+			handled = true
+			err = s.interactiveCallback(r)
+		})
+		if !handled {
+			http.Error(w, "authorization already completed", http.StatusConflict)
+			return
+		}
+		message := "Access was granted."
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err != nil {
+			message = "Authorization failed."
+			w.WriteHeader(http.StatusForbidden)
+		}
+		fmt.Fprintf(w, interactivePage, message)
+		result <- err
+	})
+	server := &http.Server{Handler: mux}
+	go server.Serve(l)
+	defer server.Close()
+
+	if err := OpenBrowser(authURL); err != nil {
+		fmt.Fprintln(os.Stderr, "Visit this URL to authorize access to Dropbox:")
+		fmt.Fprintln(os.Stderr, authURL)
+	}
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// interactiveCallback completes the authorization started by
+// AuthorizeInteractive when the browser is sent back to the callback.
+func (s *Session) interactiveCallback(r *http.Request) error {
+	if r.FormValue("not_approved") == "true" {
+		return &AuthorizationError{"callback", errors.New("user did not approve access")}
+	}
+	if r.FormValue("oauth_token") != s.RequestToken.Token {
+		return &AuthorizationError{"callback", errors.New("unknown request token")}
+	}
+	if err := s.GetAccessTokenCallback(s.RequestToken, r.FormValue("oauth_verifier")); err != nil {
+		return &AuthorizationError{"access token", err}
+	}
+	return nil
+}
+
+// Timing of AuthorizeHeadless.
+const (
+	HeadlessAuthTimeout  = 10 * time.Minute // Default time the user has to approve access
+	headlessInitialDelay = 2 * time.Second
+	headlessMaxDelay     = 30 * time.Second
+)
+
+// AuthorizeHeadless authorizes the Session on machines without a browser. It
+// writes the Dropbox authorization URL to w, for the user to visit on another
+// device, then polls for the access token, backing off between attempts,
+// until the user approves access or ctx is done. If ctx has no deadline,
+// HeadlessAuthTimeout applies. The access token is saved to the TokenStore if
+// there is one, and failing to save it is returned at once, though the Session
+// is authorized. Only refusals of the unapproved request token, network errors
+// and server errors are retried, other failures are returned at once. On
+// timeout the error of the last attempt is returned along with the context's.
+func (s *Session) AuthorizeHeadless(ctx context.Context, w io.Writer) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, HeadlessAuthTimeout)
+		defer cancel()
+	}
+
+	s.Reset()
+	authURL, err := s.GetAuthorizeURL("")
+	if err != nil {
+		return &AuthorizationError{"request token", err}
+	}
+	fmt.Fprintln(w, "Visit this URL to authorize access to Dropbox:")
+	fmt.Fprintln(w, authURL)
+
+	delay := headlessInitialDelay
+	for {
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			if err != nil {
+				return fmt.Errorf("%w (last attempt: %v)", ctx.Err(), err)
+			}
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		var retry bool
+		if retry, err = s.headlessAttempt(); !retry {
+			return err
+		}
+		if delay = delay * 3 / 2; delay > headlessMaxDelay {
+			delay = headlessMaxDelay
+		}
+	}
+}
+
+// headlessAttempt tries to exchange the request token for an access token,
+// and saves it. retry reports whether a failure is worth another attempt:
+// until the user approves, the request token is refused with a 401 status,
+// while bad app credentials would already have failed to get a request token.
+func (s *Session) headlessAttempt() (retry bool, err error) {
+	if s.RequestToken == nil {
+		return false, errors.New("no request token")
+	}
+	client := *s.client()
+	rec := &statusRecorder{base: client.Transport}
+	if rec.base == nil {
+		rec.base = http.DefaultTransport
+	}
+	client.Transport = rec
+
+	cred, _, err := s.OauthClient.RequestToken(&client, s.RequestToken.oauth(), "")
+	if err != nil {
+		switch {
+		case rec.status == 0, rec.status == http.StatusUnauthorized,
+			rec.status == http.StatusTooManyRequests, rec.status >= 500:
+			return true, err
+		}
+		return false, &AuthorizationError{"access token", err}
+	}
+	s.AccessToken = fromOauth(cred)
+	return false, s.saveAccessToken()
+}
+
+// A statusRecorder remembers the status of the last response it received.
+type statusRecorder struct {
+	base   http.RoundTripper
+	status int
+}
+
+func (r *statusRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := r.base.RoundTrip(req)
+	r.status = 0
+	if err == nil {
+		r.status = resp.StatusCode
+	}
+	return resp, err
+}
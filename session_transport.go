@@ -0,0 +1,149 @@
+package dropbox
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// ErrKeyNotPinned is returned when a server's verified certificate chain
+// contains none of a Session's PinnedKeys.
+var ErrKeyNotPinned = errors.New("server public key is not pinned")
+
+// SPKIHash returns the base64 encoded SHA-256 hash of the certificate's
+// SubjectPublicKeyInfo, the form used by Session.PinnedKeys.
+func SPKIHash(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// MaxRedirects is the number of redirects SafeRedirect follows.
+const MaxRedirects = 10
+
+// Headers carrying credentials or account selection, which are removed when a
+// redirect leaves the original host.
+var sensitiveHeaders = []string{"Authorization", TeamMemberHeader, SelectUserHeader, PathRootHeader, APIArgHeader}
+
+// SafeRedirect is the default redirect policy. It follows up to MaxRedirects
+// redirects, refuses to downgrade from https to http, and removes credentials
+// from the request whenever it is redirected to a different host.
+func SafeRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= MaxRedirects {
+		return fmt.Errorf("stopped after %d redirects", MaxRedirects)
+	}
+	orig := via[0]
+	if orig.URL.Scheme == "https" && req.URL.Scheme != "https" {
+		return errors.New("refusing redirect from https to " + req.URL.Scheme)
+	}
+	if req.URL.Host != orig.URL.Host {
+		for _, h := range sensitiveHeaders {
+			req.Header.Del(h)
+		}
+	}
+	return nil
+}
+
+// NoRedirect is a redirect policy which doesn't follow redirects, returning
+// the redirect response instead.
+func NoRedirect(req *http.Request, via []*http.Request) error {
+	return http.ErrUseLastResponse
+}
+
+// newDefaultClient builds the HTTP client used when a Session has no
+// HTTPClient, from the Session's transport settings.
+func (s *Session) newDefaultClient() *http.Client {
+	client := &http.Client{
+		Transport:     http.DefaultTransport,
+		CheckRedirect: s.CheckRedirect,
+		Timeout:       s.Timeout,
+	}
+	if client.CheckRedirect == nil {
+		client.CheckRedirect = SafeRedirect
+	}
+	if s.TLSConfig == nil && len(s.PinnedKeys) == 0 && s.Proxy == nil && !s.tunedTransport() {
+		return client
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if s.TLSConfig != nil || len(s.PinnedKeys) > 0 {
+		transport.TLSClientConfig = s.tlsConfig()
+	}
+	if s.Proxy != nil {
+		transport.Proxy = s.Proxy
+	}
+	if s.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = s.MaxIdleConnsPerHost
+		if transport.MaxIdleConns > 0 && transport.MaxIdleConns < s.MaxIdleConnsPerHost {
+			transport.MaxIdleConns = s.MaxIdleConnsPerHost
+		}
+	}
+	if s.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = s.IdleConnTimeout
+	}
+	if s.DialTimeout > 0 {
+		dialer := &net.Dialer{Timeout: s.DialTimeout, KeepAlive: 30 * time.Second}
+		transport.DialContext = dialer.DialContext
+	}
+	if s.DisableHTTP2 {
+		// A non-nil empty TLSNextProto stops the transport from
+		// negotiating HTTP/2.
+		transport.ForceAttemptHTTP2 = false
+		transport.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+	}
+	client.Transport = transport
+	return client
+}
+
+// tunedTransport reports whether any of the connection settings of the
+// default client differs from http.DefaultTransport.
+func (s *Session) tunedTransport() bool {
+	return s.MaxIdleConnsPerHost > 0 || s.IdleConnTimeout > 0 || s.DialTimeout > 0 || s.DisableHTTP2
+}
+
+// tlsConfig returns the TLS configuration for the default client, adding
+// verification of the PinnedKeys to the TLSConfig.
+func (s *Session) tlsConfig() *tls.Config {
+	config := &tls.Config{}
+	if s.TLSConfig != nil {
+		config = s.TLSConfig.Clone()
+	}
+	if len(s.PinnedKeys) == 0 {
+		return config
+	}
+
+	pins := make(map[string]bool, len(s.PinnedKeys))
+	for _, pin := range s.PinnedKeys {
+		pins[pin] = true
+	}
+	verify := config.VerifyConnection
+	config.VerifyConnection = func(cs tls.ConnectionState) error {
+		if verify != nil {
+			if err := verify(cs); err != nil {
+				return err
+			}
+		}
+		// Only verified certificates count: the server may send others,
+		// which verification ignores. Without verification, there is
+		// nothing but the server's own certificate to trust.
+		var certs []*x509.Certificate
+		for _, chain := range cs.VerifiedChains {
+			certs = append(certs, chain...)
+		}
+		if len(cs.VerifiedChains) == 0 && len(cs.PeerCertificates) > 0 {
+			certs = cs.PeerCertificates[:1]
+		}
+		for _, cert := range certs {
+			if pins[SPKIHash(cert)] {
+				return nil
+			}
+		}
+		return ErrKeyNotPinned
+	}
+	return config
+}
@@ -0,0 +1,97 @@
+package dropbox
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// newCert creates a certificate for name, signed by parent, or self-signed if
+// parent is nil.
+func newCert(t *testing.T, name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  parent == nil,
+		BasicConstraintsValid: true,
+	}
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func TestPinnedKeys(t *testing.T) {
+	ca, caKey := newCert(t, "ca", nil, nil)
+	leaf, _ := newCert(t, "leaf", ca, caKey)
+	pinned, _ := newCert(t, "pinned", nil, nil)
+
+	tests := []struct {
+		name string
+		pin  *x509.Certificate
+		cs   tls.ConnectionState
+		ok   bool
+	}{
+		{
+			name: "pinned CA in verified chain",
+			pin:  ca,
+			cs:   tls.ConnectionState{PeerCertificates: []*x509.Certificate{leaf}, VerifiedChains: [][]*x509.Certificate{{leaf, ca}}},
+			ok:   true,
+		},
+		{
+			name: "pinned leaf in verified chain",
+			pin:  leaf,
+			cs:   tls.ConnectionState{PeerCertificates: []*x509.Certificate{leaf}, VerifiedChains: [][]*x509.Certificate{{leaf, ca}}},
+			ok:   true,
+		},
+		{
+			name: "pinned cert appended to the chain sent",
+			pin:  pinned,
+			cs:   tls.ConnectionState{PeerCertificates: []*x509.Certificate{leaf, pinned}, VerifiedChains: [][]*x509.Certificate{{leaf, ca}}},
+		},
+		{
+			name: "unverified leaf",
+			pin:  leaf,
+			cs:   tls.ConnectionState{PeerCertificates: []*x509.Certificate{leaf, pinned}},
+			ok:   true,
+		},
+		{
+			name: "unverified chain",
+			pin:  pinned,
+			cs:   tls.ConnectionState{PeerCertificates: []*x509.Certificate{leaf, pinned}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Session{PinnedKeys: []string{SPKIHash(tt.pin)}}
+			err := s.tlsConfig().VerifyConnection(tt.cs)
+			if tt.ok && err != nil {
+				t.Errorf("VerifyConnection = %v, want success", err)
+			}
+			if !tt.ok && err != ErrKeyNotPinned {
+				t.Errorf("VerifyConnection = %v, want %v", err, ErrKeyNotPinned)
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package dropbox
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// PendingAuthTimeout is how long a WebAuth remembers a request token while
+// waiting for the user to return from the Dropbox authorization page.
+const PendingAuthTimeout = time.Hour
+
+// StateCookie is the name of the cookie WebAuth uses to bind the CSRF state
+// value to the user's browser.
+const StateCookie = "dropbox_auth_state"
+
+// A WebAuth provides ready-made http.Handlers which perform the OAuth
+// authorization cycle for web applications. The StartHandler redirects the user
+// to Dropbox, and the CallbackHandler completes authorization when the user is
+// sent back, passing the authorized Session to OnAuthorized.
+//
+// A random state value is attached to each authorization, stored in a cookie,
+// and verified on callback to protect against login CSRF.
+type WebAuth struct {
+	// NewSession returns a new unauthorized Session for each user starting
+	// authorization, typically by calling NewSession with the app's key.
+	NewSession func() *Session
+
+	// CallbackURL is the absolute URL the CallbackHandler is served at.
+	CallbackURL string
+
+	// OnAuthorized is called with the authorized Session once the user
+	// has been returned to the CallbackHandler. It is responsible for
+	// writing the response. If it is nil, a plain page telling the user
+	// access was granted is written, and the Session is only kept by its
+	// TokenStore, if it has one.
+	OnAuthorized func(w http.ResponseWriter, r *http.Request, s *Session)
+
+	// OnError, if set, is called to write the response when authorization
+	// fails. Otherwise a plain error response is written.
+	OnError func(w http.ResponseWriter, r *http.Request, err error)
+
+	mu      sync.Mutex
+	pending map[string]pendingAuth
+}
+
+type pendingAuth struct {
+	session *Session
+	state   string
+	started time.Time
+}
+
+// StartHandler returns a handler which begins authorization by redirecting
+// the user to the Dropbox authorization page.
+func (wa *WebAuth) StartHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		state, err := newState()
+		if err != nil {
+			wa.fail(w, r, &AuthorizationError{"state", err})
+			return
+		}
+
+		s := wa.NewSession()
+		url, err := s.GetAuthorizeURLWithState(wa.CallbackURL, state)
+		if err != nil {
+			wa.fail(w, r, &AuthorizationError{"request token", err})
+			return
+		}
+		wa.remember(s, state)
+		http.SetCookie(w, stateCookie(r, state, int(PendingAuthTimeout/time.Second)))
+		http.Redirect(w, r, url, http.StatusFound)
+	})
+}
+
+// CallbackHandler returns a handler which completes authorization when the
+// user is returned from Dropbox, and calls OnAuthorized.
+func (wa *WebAuth) CallbackHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("not_approved") == "true" {
+			wa.fail(w, r, &AuthorizationError{"callback", errors.New("user did not approve access")})
+			return
+		}
+
+		p, ok := wa.recall(r.FormValue("oauth_token"))
+		if !ok {
+			wa.fail(w, r, &AuthorizationError{"callback", errors.New("unknown or expired request token")})
+			return
+		}
+		if !checkState(r, p.state) {
+			wa.fail(w, r, &AuthorizationError{"callback", errors.New("state mismatch")})
+			return
+		}
+		http.SetCookie(w, stateCookie(r, "", -1))
+
+		s := p.session
+		if err := s.GetAccessTokenCallback(s.RequestToken, r.FormValue("oauth_verifier")); err != nil {
+			wa.fail(w, r, &AuthorizationError{"access token", err})
+			return
+		}
+		if wa.OnAuthorized == nil {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			io.WriteString(w, "Access to Dropbox was granted.\n")
+			return
+		}
+		wa.OnAuthorized(w, r, s)
+	})
+}
+
+// stateCookie returns the cookie carrying the state value, or deleting it if
+// maxAge is negative. Its path is "/", so it reaches the callback wherever it
+// is mounted, and it is sent on the top level navigation back from Dropbox.
+func stateCookie(r *http.Request, state string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     StateCookie,
+		Value:    state,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+func (wa *WebAuth) fail(w http.ResponseWriter, r *http.Request, err error) {
+	if wa.OnError != nil {
+		wa.OnError(w, r, err)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusForbidden)
+}
+
+// remember stores the session until its request token is returned to the
+// callback, and discards any sessions which have been pending too long.
+func (wa *WebAuth) remember(s *Session, state string) {
+	wa.mu.Lock()
+	defer wa.mu.Unlock()
+
+	now := time.Now()
+	if wa.pending == nil {
+		wa.pending = make(map[string]pendingAuth)
+	}
+	for token, p := range wa.pending {
+		if now.Sub(p.started) > PendingAuthTimeout {
+			delete(wa.pending, token)
+		}
+	}
+	wa.pending[s.RequestToken.Token] = pendingAuth{s, state, now}
+}
+
+// recall removes and returns the pending authorization for the given request
+// token.
+func (wa *WebAuth) recall(token string) (pendingAuth, bool) {
+	wa.mu.Lock()
+	defer wa.mu.Unlock()
+
+	p, ok := wa.pending[token]
+	if !ok {
+		return p, false
+	}
+	delete(wa.pending, token)
+	return p, time.Since(p.started) <= PendingAuthTimeout
+}
+
+func newState() (string, error) {
+	b := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// checkState reports whether both the state parameter and the state cookie of
+// the request match the expected value.
+func checkState(r *http.Request, expected string) bool {
+	cookie, err := r.Cookie(StateCookie)
+	if err != nil {
+		return false
+	}
+	param := r.FormValue("state")
+	return subtle.ConstantTimeCompare([]byte(param), []byte(expected)) == 1 &&
+		subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(expected)) == 1
+}
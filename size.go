@@ -0,0 +1,71 @@
+package dropbox
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var sizeUnits = []string{"bytes", "KB", "MB", "GB", "TB", "PB", "EB"}
+
+// ParseSize converts a human readable size, as found in Metadata.Size (eg:
+// "225.4KB", "2.3 MB", "1 byte"), into a number of bytes. Units are powers of
+// 1024, and a comma may be used as the decimal separator.
+func ParseSize(s string) (int64, error) {
+	str := strings.TrimSpace(s)
+	i := strings.IndexFunc(str, func(r rune) bool {
+		return !unicode.IsDigit(r) && r != '.' && r != ','
+	})
+	if i < 0 {
+		i = len(str)
+	}
+	num := strings.Replace(str[:i], ",", ".", 1)
+	unit := strings.TrimSpace(str[i:])
+
+	value, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+
+	switch strings.ToUpper(unit) {
+	case "", "B", "BYTE", "BYTES":
+		return int64(value), nil
+	}
+	for exp, name := range sizeUnits[1:] {
+		if strings.EqualFold(unit, name) {
+			return int64(value * float64(int64(1)<<(10*uint(exp+1)))), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid size unit in %q", s)
+}
+
+// FormatSize formats a number of bytes in the same style as Metadata.Size,
+// eg: "0 bytes", "1 byte", "225.4 KB".
+func FormatSize(n int64) string {
+	if n == 1 {
+		return "1 byte"
+	}
+	if n < 1024 {
+		return strconv.FormatInt(n, 10) + " bytes"
+	}
+	value, exp := float64(n), 0
+	for value >= 1024 && exp < len(sizeUnits)-1 {
+		value /= 1024
+		exp++
+	}
+	return strconv.FormatFloat(value, 'f', 1, 64) + " " + sizeUnits[exp]
+}
+
+// SizeBytes returns the size of the file in bytes. It prefers the Bytes field,
+// but falls back to parsing Size if Bytes is not set.
+func (m *Metadata) SizeBytes() int64 {
+	if m.Bytes != 0 || m.Size == "" {
+		return m.Bytes
+	}
+	n, err := ParseSize(m.Size)
+	if err != nil {
+		return 0
+	}
+	return n
+}
@@ -0,0 +1,228 @@
+package dropbox
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"sync"
+)
+
+// A Snapshot is an in-memory copy of the metadata of a dropbox, kept current
+// by applying the pages of the delta call in order, according to the rules
+// Dropbox gives for them: paths are compared ignoring case, a reset discards
+// everything first, an entry with metadata replaces whatever was at its path,
+// creating missing parent folders, though a folder keeps its contents when its
+// metadata is updated, and a deletion removes the path with everything below
+// it. A Snapshot can be saved to a file and loaded again, to resume from its
+// cursor. It is safe for concurrent use. The zero value is an empty snapshot.
+type Snapshot struct {
+	mu       sync.RWMutex
+	cursor   string
+	entries  map[string]*Metadata           // by normalized path
+	children map[string]map[string]struct{} // normalized paths of the entries of each folder
+}
+
+// NewSnapshot creates an empty Snapshot.
+func NewSnapshot() *Snapshot {
+	return &Snapshot{}
+}
+
+// ApplyDelta applies a page of changes to the snapshot, and records its
+// cursor, from which the next page should be requested.
+func (s *Snapshot) ApplyDelta(d *Delta) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if d.Reset {
+		s.reset()
+	}
+	for _, e := range d.Entries {
+		s.apply(e)
+	}
+	s.cursor = d.Cursor
+}
+
+// Sync applies all the changes since the cursor of the snapshot, retrieved
+// with c. The first Sync of an empty snapshot copies the whole dropbox.
+func (s *Snapshot) Sync(c *Client) error {
+	for {
+		d, err := c.Delta(s.Cursor())
+		if err != nil {
+			return err
+		}
+		s.ApplyDelta(d)
+		if !d.HasMore {
+			return nil
+		}
+	}
+}
+
+// Cursor returns the cursor of the last page applied.
+func (s *Snapshot) Cursor() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cursor
+}
+
+// Len returns the number of files and folders in the snapshot.
+func (s *Snapshot) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.entries)
+}
+
+// Stat returns the metadata of the file or folder at p, if there is one.
+func (s *Snapshot) Stat(p string) (*Metadata, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	meta, ok := s.entries[NormalizePath(p)]
+	if !ok {
+		return nil, false
+	}
+	m := *meta
+	return &m, true
+}
+
+// ReadDir returns the entries of the folder at p, sorted by path ignoring
+// case. The root folder is "/".
+func (s *Snapshot) ReadDir(p string) []Metadata {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	keys := make([]string, 0, len(s.children[NormalizePath(p)]))
+	for k := range s.children[NormalizePath(p)] {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	list := make([]Metadata, len(keys))
+	for i, k := range keys {
+		list[i] = *s.entries[k]
+	}
+	return list
+}
+
+func (s *Snapshot) reset() {
+	s.entries = make(map[string]*Metadata)
+	s.children = make(map[string]map[string]struct{})
+}
+
+func (s *Snapshot) apply(e Entry) {
+	if s.entries == nil {
+		s.reset()
+	}
+	key := NormalizePath(e.Path)
+	if key == "/" {
+		return
+	}
+	if e.Meta == nil {
+		s.remove(key)
+		return
+	}
+
+	old, exists := s.entries[key]
+	if exists && !(old.IsDir && e.Meta.IsDir) {
+		// A file replaces anything, and a folder replaces a file.
+		s.remove(key)
+	}
+	meta := *e.Meta
+	meta.Contents = nil
+	if meta.Path == "" {
+		meta.Path = e.Path
+	}
+	s.mkdirAll(path.Dir(meta.Path))
+	s.entries[key] = &meta
+	s.link(key)
+}
+
+// mkdirAll creates the folder at p and its parents, if missing. Like the
+// entries creating them, files in the way are replaced.
+func (s *Snapshot) mkdirAll(p string) {
+	key := NormalizePath(p)
+	if key == "/" {
+		return
+	}
+	if meta, ok := s.entries[key]; ok && meta.IsDir {
+		return
+	} else if ok {
+		s.remove(key)
+	}
+	s.mkdirAll(path.Dir(p))
+	s.entries[key] = &Metadata{Path: path.Clean("/" + p), IsDir: true}
+	s.link(key)
+}
+
+// link adds key to the entries of its parent folder.
+func (s *Snapshot) link(key string) {
+	dir := path.Dir(key)
+	if s.children[dir] == nil {
+		s.children[dir] = make(map[string]struct{})
+	}
+	s.children[dir][key] = struct{}{}
+}
+
+// remove deletes the entry at key and everything below it.
+func (s *Snapshot) remove(key string) {
+	for child := range s.children[key] {
+		s.remove(child)
+	}
+	delete(s.children, key)
+	delete(s.entries, key)
+	if siblings := s.children[path.Dir(key)]; siblings != nil {
+		delete(siblings, key)
+		if len(siblings) == 0 {
+			delete(s.children, path.Dir(key))
+		}
+	}
+}
+
+// snapshotFile is the form in which a Snapshot is saved.
+type snapshotFile struct {
+	Cursor  string     `json:"cursor"`
+	Entries []Metadata `json:"entries"`
+}
+
+// Save writes the snapshot to the named file.
+func (s *Snapshot) Save(filename string) error {
+	s.mu.RLock()
+	f := snapshotFile{Cursor: s.cursor, Entries: make([]Metadata, 0, len(s.entries))}
+	for _, meta := range s.entries {
+		f.Entries = append(f.Entries, *meta)
+	}
+	s.mu.RUnlock()
+
+	data, err := json.Marshal(&f)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filename, data, 0600)
+}
+
+// LoadSnapshot reads a Snapshot saved by Save. A missing file results in an
+// empty snapshot.
+func LoadSnapshot(filename string) (*Snapshot, error) {
+	s := NewSnapshot()
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var f snapshotFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	s.ApplyDelta(&Delta{Reset: true, Cursor: f.Cursor, Entries: snapshotEntries(f.Entries)})
+	return s, nil
+}
+
+// snapshotEntries returns the delta entries recreating a saved snapshot,
+// parents first.
+func snapshotEntries(list []Metadata) []Entry {
+	sort.Slice(list, func(i, j int) bool { return NormalizePath(list[i].Path) < NormalizePath(list[j].Path) })
+	entries := make([]Entry, len(list))
+	for i := range list {
+		entries[i] = Entry{Path: NormalizePath(list[i].Path), Meta: &list[i]}
+	}
+	return entries
+}
@@ -0,0 +1,159 @@
+package dropbox
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func fileEntry(p, rev string) Entry {
+	return Entry{Path: NormalizePath(p), Meta: &Metadata{Path: p, Rev: rev}}
+}
+
+func folderEntry(p string) Entry {
+	return Entry{Path: NormalizePath(p), Meta: &Metadata{Path: p, IsDir: true}}
+}
+
+func deletedEntry(p string) Entry {
+	return Entry{Path: NormalizePath(p)}
+}
+
+// snapshotPaths returns the paths of the folder p of s and everything below
+// it, depth first.
+func snapshotPaths(s *Snapshot, p string) []string {
+	var paths []string
+	for _, meta := range s.ReadDir(p) {
+		paths = append(paths, meta.Path)
+		if meta.IsDir {
+			paths = append(paths, snapshotPaths(s, meta.Path)...)
+		}
+	}
+	return paths
+}
+
+func TestSnapshotApplyDelta(t *testing.T) {
+	tests := []struct {
+		name  string
+		pages []*Delta
+		want  []string
+	}{
+		{
+			name: "implicit parents",
+			pages: []*Delta{
+				{Entries: []Entry{fileEntry("/a/b/c.txt", "1")}},
+			},
+			want: []string{"/a", "/a/b", "/a/b/c.txt"},
+		},
+		{
+			name: "reset discards everything",
+			pages: []*Delta{
+				{Entries: []Entry{fileEntry("/old.txt", "1"), folderEntry("/Docs"), fileEntry("/Docs/x", "2")}},
+				{Reset: true, Entries: []Entry{fileEntry("/new.txt", "3")}},
+			},
+			want: []string{"/new.txt"},
+		},
+		{
+			name: "deletion removes the subtree",
+			pages: []*Delta{
+				{Entries: []Entry{folderEntry("/Docs"), fileEntry("/Docs/a", "1"), folderEntry("/Docs/sub"), fileEntry("/Docs/sub/b", "2"), fileEntry("/keep", "3")}},
+				{Entries: []Entry{deletedEntry("/docs")}},
+			},
+			want: []string{"/keep"},
+		},
+		{
+			name: "file replaces folder and its contents",
+			pages: []*Delta{
+				{Entries: []Entry{folderEntry("/x"), fileEntry("/x/inner", "1")}},
+				{Entries: []Entry{fileEntry("/x", "2")}},
+			},
+			want: []string{"/x"},
+		},
+		{
+			name: "folder replaces file",
+			pages: []*Delta{
+				{Entries: []Entry{fileEntry("/x", "1")}},
+				{Entries: []Entry{folderEntry("/x"), fileEntry("/x/inner", "2")}},
+			},
+			want: []string{"/x", "/x/inner"},
+		},
+		{
+			name: "folder update keeps contents",
+			pages: []*Delta{
+				{Entries: []Entry{folderEntry("/x"), fileEntry("/x/inner", "1")}},
+				{Entries: []Entry{folderEntry("/X")}},
+			},
+			want: []string{"/X", "/x/inner"},
+		},
+		{
+			name: "implicit parent replaces file",
+			pages: []*Delta{
+				{Entries: []Entry{fileEntry("/a", "1")}},
+				{Entries: []Entry{fileEntry("/a/b", "2")}},
+			},
+			want: []string{"/a", "/a/b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSnapshot()
+			for _, d := range tt.pages {
+				s.ApplyDelta(d)
+			}
+			if got := snapshotPaths(s, "/"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("paths = %q, want %q", got, tt.want)
+			}
+			if s.Len() != len(tt.want) {
+				t.Errorf("Len = %d, want %d", s.Len(), len(tt.want))
+			}
+		})
+	}
+}
+
+func TestSnapshotStat(t *testing.T) {
+	s := NewSnapshot()
+	s.ApplyDelta(&Delta{Cursor: "c1", Entries: []Entry{fileEntry("/Photos/Cat.jpg", "7")}})
+
+	meta, ok := s.Stat("/photos/CAT.JPG")
+	if !ok || meta.Rev != "7" || meta.Path != "/Photos/Cat.jpg" {
+		t.Errorf("Stat = %+v, %v", meta, ok)
+	}
+	if meta, ok := s.Stat("/photos"); !ok || !meta.IsDir {
+		t.Errorf("Stat of implicit parent = %+v, %v", meta, ok)
+	}
+	if _, ok := s.Stat("/missing"); ok {
+		t.Error("Stat of a missing path succeeded")
+	}
+	if s.Cursor() != "c1" {
+		t.Errorf("Cursor = %q, want c1", s.Cursor())
+	}
+}
+
+func TestSnapshotSaveLoad(t *testing.T) {
+	s := NewSnapshot()
+	s.ApplyDelta(&Delta{Cursor: "c2", Entries: []Entry{
+		folderEntry("/A"), fileEntry("/A/one", "1"), fileEntry("/A/B/two", "2"), fileEntry("/three", "3"),
+	}})
+	filename := filepath.Join(t.TempDir(), "snapshot.json")
+	if err := s.Save(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadSnapshot(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Cursor() != "c2" {
+		t.Errorf("Cursor = %q, want c2", loaded.Cursor())
+	}
+	if got, want := snapshotPaths(loaded, "/"), snapshotPaths(s, "/"); !reflect.DeepEqual(got, want) {
+		t.Errorf("loaded paths = %q, want %q", got, want)
+	}
+	if meta, _ := loaded.Stat("/a/b/two"); meta == nil || meta.Rev != "2" {
+		t.Errorf("loaded Stat = %+v", meta)
+	}
+
+	empty, err := LoadSnapshot(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil || empty.Len() != 0 {
+		t.Errorf("LoadSnapshot of a missing file = %d entries, %v", empty.Len(), err)
+	}
+}
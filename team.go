@@ -0,0 +1,138 @@
+package dropbox
+
+// URLs for the Dropbox for Business team API calls
+const (
+	TeamInfoURL           = APIPrefix + "/team/get_info"
+	TeamMembersListURL    = APIPrefix + "/team/members/list"
+	TeamMembersGetInfoURL = APIPrefix + "/team/members/get_info"
+)
+
+// TeamMemberHeader is the header which selects the team member a
+// team-authorized request acts on behalf of.
+const TeamMemberHeader = "X-Dropbox-Perform-As-Team-Member"
+
+// A TeamClient provides access to the Dropbox for Business team services. It
+// must use a Session authorized with a team token, typically an OAuth2Token.
+type TeamClient struct {
+	client *Client
+}
+
+// NewTeamClient creates a new team client using the given authorized session.
+// Passing an unauthorized session will cause NewTeamClient to panic.
+func NewTeamClient(session *Session) *TeamClient {
+	return &TeamClient{client: NewClient(session, DropboxRoot)}
+}
+
+// A TeamInfo represents the information about a team.
+type TeamInfo struct {
+	Name                string `json:"name"`
+	TeamID              string `json:"team_id"`
+	NumLicensedUsers    int    `json:"num_licensed_users"`
+	NumProvisionedUsers int    `json:"num_provisioned_users"`
+}
+
+// A TeamMemberProfile represents the profile of a member of a team.
+type TeamMemberProfile struct {
+	MemberID      string   `json:"member_id"`
+	Email         string   `json:"email"`
+	EmailVerified bool     `json:"email_verified"`
+	Status        string   `json:"status"`
+	GivenName     string   `json:"given_name"`
+	Surname       string   `json:"surname"`
+	ExternalID    string   `json:"external_id"`
+	Groups        []string `json:"groups"`
+}
+
+// A TeamMember represents a member of a team and their permissions.
+type TeamMember struct {
+	Profile     TeamMemberProfile `json:"profile"`
+	Permissions struct {
+		IsAdmin bool `json:"is_admin"`
+	} `json:"permissions"`
+}
+
+// A TeamMembers represents one page of a listing of the members of a team.
+type TeamMembers struct {
+	Members []TeamMember `json:"members"`
+	Cursor  string       `json:"cursor"`
+	HasMore bool         `json:"has_more"`
+}
+
+// Info returns information about the team.
+func (tc *TeamClient) Info() (info *TeamInfo, err error) {
+	err = tc.client.postJSON(TeamInfoURL, struct{}{}, &info)
+	return
+}
+
+// Members returns up to limit (or the default # if 0) members of the team. If
+// cursor is not the empty string, the listing continues from a previous page.
+func (tc *TeamClient) Members(limit int, cursor string) (members *TeamMembers, err error) {
+	arg := struct {
+		Limit  int    `json:"limit,omitempty"`
+		Cursor string `json:"cursor,omitempty"`
+	}{limit, cursor}
+	err = tc.client.postJSON(TeamMembersListURL, arg, &members)
+	return
+}
+
+// MemberInfo returns the profile of the team member with the given id.
+func (tc *TeamClient) MemberInfo(memberID string) (member *TeamMember, err error) {
+	arg := struct {
+		MemberID string `json:"member_id"`
+	}{memberID}
+	err = tc.client.postJSON(TeamMembersGetInfoURL, arg, &member)
+	return
+}
+
+// MemberClient returns a Client which accesses the files of the team member
+// with the given id, on the given Dropbox Root.
+func (tc *TeamClient) MemberClient(memberID string, root AccessRoot) *Client {
+	return tc.client.WithRoot(root).AsMember(memberID)
+}
+
+// PathRootHeader is the header which selects the namespace that paths in a
+// request are relative to.
+const PathRootHeader = "Dropbox-API-Path-Root"
+
+// A PathRoot selects the namespace paths are resolved against, allowing team
+// spaces and shared namespaces to be addressed explicitly. The zero value is
+// the member's home namespace.
+type PathRoot struct {
+	tag, id string
+}
+
+// HomePathRoot resolves paths against the member's home namespace.
+func HomePathRoot() PathRoot {
+	return PathRoot{}
+}
+
+// RootPathRoot resolves paths against the given root namespace, typically the
+// root of a team space. Requests fail if the user's root namespace has changed
+// from nsID.
+func RootPathRoot(nsID string) PathRoot {
+	return PathRoot{"root", nsID}
+}
+
+// NamespacePathRoot resolves paths against the given namespace, such as a
+// shared folder the user has access to.
+func NamespacePathRoot(nsID string) PathRoot {
+	return PathRoot{"namespace_id", nsID}
+}
+
+// header returns the value of PathRootHeader selecting pr.
+func (pr PathRoot) header() string {
+	arg := struct {
+		Tag         string `json:".tag"`
+		Root        string `json:"root,omitempty"`
+		NamespaceID string `json:"namespace_id,omitempty"`
+	}{Tag: "home"}
+	switch pr.tag {
+	case "root":
+		arg.Tag, arg.Root = pr.tag, pr.id
+	case "namespace_id":
+		arg.Tag, arg.NamespaceID = pr.tag, pr.id
+	}
+	// A struct of strings always encodes.
+	value, _ := apiArg(arg)
+	return value
+}
@@ -0,0 +1,210 @@
+package dropbox
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ErrTokenNotFound is returned by a TokenStore when no credentials are stored
+// for the requested account.
+var ErrTokenNotFound = errors.New("token not found")
+
+// A TokenStore persists access credentials keyed by an account name, allowing
+// a Session to be authorized once and reused across program runs.
+type TokenStore interface {
+	Get(account string) (*Credentials, error)
+	Put(account string, cred *Credentials) error
+	Delete(account string) error
+}
+
+// A MemoryTokenStore is a TokenStore which keeps credentials in memory. It is
+// safe for concurrent use. The zero value is an empty store.
+type MemoryTokenStore struct {
+	mu     sync.Mutex
+	tokens map[string]Credentials
+}
+
+// Get returns the credentials stored for account.
+func (m *MemoryTokenStore) Get(account string) (*Credentials, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cred, ok := m.tokens[account]
+	if !ok {
+		return nil, ErrTokenNotFound
+	}
+	return &cred, nil
+}
+
+// Put stores a copy of cred for account.
+func (m *MemoryTokenStore) Put(account string, cred *Credentials) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.tokens == nil {
+		m.tokens = make(map[string]Credentials)
+	}
+	m.tokens[account] = *cred
+	return nil
+}
+
+// Delete removes the credentials for account, if any.
+func (m *MemoryTokenStore) Delete(account string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.tokens, account)
+	return nil
+}
+
+// A FileTokenStore is a TokenStore which keeps credentials for all accounts in
+// a single JSON file. The file is created with permissions that only allow the
+// current user access, and is replaced atomically on every change.
+type FileTokenStore struct {
+	Path string
+	mu   sync.Mutex
+}
+
+// NewFileTokenStore returns a FileTokenStore using the file at path.
+func NewFileTokenStore(path string) *FileTokenStore {
+	return &FileTokenStore{Path: path}
+}
+
+func (f *FileTokenStore) load() (map[string]*Credentials, error) {
+	tokens := make(map[string]*Credentials)
+	data, err := ioutil.ReadFile(f.Path)
+	if os.IsNotExist(err) {
+		return tokens, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func (f *FileTokenStore) save(tokens map[string]*Credentials) error {
+	data, err := json.MarshalIndent(tokens, "", "\t")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(f.Path, data, 0600)
+}
+
+// Get returns the credentials stored for account.
+func (f *FileTokenStore) Get(account string) (*Credentials, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	tokens, err := f.load()
+	if err != nil {
+		return nil, err
+	}
+	cred, ok := tokens[account]
+	if !ok || cred == nil {
+		return nil, ErrTokenNotFound
+	}
+	return cred, nil
+}
+
+// Put stores cred for account, replacing any previous credentials.
+func (f *FileTokenStore) Put(account string, cred *Credentials) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	tokens, err := f.load()
+	if err != nil {
+		return err
+	}
+	tokens[account] = cred
+	return f.save(tokens)
+}
+
+// Delete removes the credentials for account, if any.
+func (f *FileTokenStore) Delete(account string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	tokens, err := f.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := tokens[account]; !ok {
+		return nil
+	}
+	delete(tokens, account)
+	return f.save(tokens)
+}
+
+// A Keyring is a minimal interface to an operating system's secret storage
+// (eg: macOS Keychain, Windows Credential Manager or the Secret Service API).
+// Get must return ErrTokenNotFound if there is no matching secret.
+type Keyring interface {
+	Get(service, user string) (string, error)
+	Set(service, user, secret string) error
+	Delete(service, user string) error
+}
+
+// A KeyringTokenStore is a TokenStore which keeps credentials in a Keyring,
+// under the given service name, with one entry per account.
+type KeyringTokenStore struct {
+	Service string
+	Keyring Keyring
+}
+
+// Get returns the credentials stored for account.
+func (k *KeyringTokenStore) Get(account string) (*Credentials, error) {
+	secret, err := k.Keyring.Get(k.Service, account)
+	if err != nil {
+		return nil, err
+	}
+	var cred Credentials
+	if err := json.Unmarshal([]byte(secret), &cred); err != nil {
+		return nil, err
+	}
+	return &cred, nil
+}
+
+// Put stores cred for account, replacing any previous credentials.
+func (k *KeyringTokenStore) Put(account string, cred *Credentials) error {
+	data, err := json.Marshal(cred)
+	if err != nil {
+		return err
+	}
+	return k.Keyring.Set(k.Service, account, string(data))
+}
+
+// Delete removes the credentials for account.
+func (k *KeyringTokenStore) Delete(account string) error {
+	return k.Keyring.Delete(k.Service, account)
+}
+
+// writeFileAtomic writes data to a temporary file in the same directory as
+// filename, syncs it, then renames it over filename so that readers never see
+// a partially written file.
+func writeFileAtomic(filename string, data []byte, perm os.FileMode) error {
+	f, err := ioutil.TempFile(filepath.Dir(filename), "."+filepath.Base(filename)+".tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Chmod(tmp, perm); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
@@ -0,0 +1,538 @@
+package dropbox
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// A TransferState is the stage a Transfer has reached.
+type TransferState int
+
+// The states of a Transfer. Done, Failed and Canceled are final.
+const (
+	TransferQueued TransferState = iota
+	TransferRunning
+	TransferDone
+	TransferFailed
+	TransferCanceled
+)
+
+var transferStateNames = []string{"queued", "running", "done", "failed", "canceled"}
+
+func (s TransferState) String() string {
+	if s >= 0 && int(s) < len(transferStateNames) {
+		return transferStateNames[s]
+	}
+	return fmt.Sprintf("TransferState(%d)", int(s))
+}
+
+// ErrQueueClosed is the error of transfers canceled by closing their queue.
+var ErrQueueClosed = errors.New("transfer queue closed")
+
+// A Transfer is an upload or download of a local file, managed by a queue.
+// Its fields must not be changed once it has been queued.
+type Transfer struct {
+	ID       int64
+	Upload   bool // true for uploads, false for downloads
+	Local    string
+	Remote   string
+	Rev      string         // revision to download, the latest if empty
+	Options  *UploadOptions // options of an upload
+	Priority int
+
+	size        int64 // accessed atomically
+	transferred int64 // accessed atomically
+
+	resumed bool // the upload continues a session from a previous run
+
+	mu      sync.Mutex
+	state   TransferState
+	meta    *Metadata
+	err     error
+	done    chan struct{}
+	current *ChunkedUpload // the session of a chunked upload in progress
+}
+
+func newTransfer() *Transfer {
+	return &Transfer{done: make(chan struct{})}
+}
+
+// State returns the current state of the transfer.
+func (t *Transfer) State() TransferState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.state
+}
+
+// Progress returns the number of bytes transferred so far, and the size of
+// the file, which is zero for a download that hasn't started.
+func (t *Transfer) Progress() (transferred, size int64) {
+	return atomic.LoadInt64(&t.transferred), atomic.LoadInt64(&t.size)
+}
+
+// Done returns a channel which is closed when the transfer reaches a final
+// state.
+func (t *Transfer) Done() <-chan struct{} {
+	return t.done
+}
+
+// Wait waits for the transfer to finish, and returns the metadata of the
+// remote file, or the error which made it fail.
+func (t *Transfer) Wait() (*Metadata, error) {
+	<-t.done
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.meta, t.err
+}
+
+func (t *Transfer) setState(state TransferState, meta *Metadata, err error) {
+	t.mu.Lock()
+	t.state = state
+	t.meta = meta
+	t.err = err
+	t.mu.Unlock()
+	if state >= TransferDone {
+		close(t.done)
+	}
+}
+
+func (t *Transfer) session() *ChunkedUpload {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.current == nil {
+		return nil
+	}
+	session := *t.current
+	return &session
+}
+
+func (t *Transfer) setSession(session *ChunkedUpload) {
+	t.mu.Lock()
+	t.current = session
+	t.mu.Unlock()
+}
+
+// count records that n more bytes have been transferred.
+func (t *Transfer) count(n int) {
+	atomic.AddInt64(&t.transferred, int64(n))
+}
+
+// A progressReader counts the bytes read from it as transferred.
+type progressReader struct {
+	r *os.File
+	t *Transfer
+}
+
+func (pr progressReader) Read(p []byte) (int, error) {
+	n, err := pr.r.Read(p)
+	pr.t.count(n)
+	return n, err
+}
+
+// A progressWriter counts the bytes written to it as transferred.
+type progressWriter struct {
+	w *os.File
+	t *Transfer
+}
+
+func (pw progressWriter) Write(p []byte) (int, error) {
+	n, err := pw.w.Write(p)
+	pw.t.count(n)
+	return n, err
+}
+
+// transferHeap orders queued transfers by decreasing priority, then by the
+// order they were added in.
+type transferHeap []*Transfer
+
+func (h transferHeap) Len() int { return len(h) }
+func (h transferHeap) Less(i, j int) bool {
+	if h[i].Priority != h[j].Priority {
+		return h[i].Priority > h[j].Priority
+	}
+	return h[i].ID < h[j].ID
+}
+func (h transferHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *transferHeap) Push(x interface{}) { *h = append(*h, x.(*Transfer)) }
+func (h *transferHeap) Pop() interface{} {
+	old := *h
+	t := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return t
+}
+
+// A TransferQueue runs transfers with a fixed number of workers, starting
+// those with the highest priority first. It can be paused, which stops new
+// transfers from starting while running ones complete.
+type TransferQueue struct {
+	// OnUpdate, if set, is called whenever a transfer changes state. It
+	// must be set before the first transfer is added, and is called from
+	// the workers, so it must not block for long.
+	OnUpdate func(t *Transfer)
+
+	run func(t *Transfer) (*Metadata, error)
+
+	// checkpoint, if set, is called when the persistent state of a
+	// transfer changes.
+	checkpoint func(t *Transfer)
+
+	// control, if set, limits how many of the workers run transfers at once.
+	control *ConcurrencyControl
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	pending transferHeap
+	all     []*Transfer
+	nextID  int64
+	paused  bool
+	closed  bool
+	wg      sync.WaitGroup
+}
+
+func newTransferQueue(workers int, control *ConcurrencyControl, run func(t *Transfer) (*Metadata, error)) *TransferQueue {
+	if workers < 1 {
+		workers = 1
+	}
+	q := &TransferQueue{run: run, control: control}
+	q.cond = sync.NewCond(&q.mu)
+	q.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go q.worker()
+	}
+	return q
+}
+
+// add queues t, giving it an id.
+func (q *TransferQueue) add(t *Transfer) *Transfer {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		q.update(t, TransferCanceled, nil, ErrQueueClosed)
+		return t
+	}
+	q.nextID++
+	t.ID = q.nextID
+	q.all = append(q.all, t)
+	heap.Push(&q.pending, t)
+	q.cond.Signal()
+	q.mu.Unlock()
+
+	q.notify(t)
+	return t
+}
+
+func (q *TransferQueue) update(t *Transfer, state TransferState, meta *Metadata, err error) {
+	t.setState(state, meta, err)
+	q.notify(t)
+}
+
+func (q *TransferQueue) notify(t *Transfer) {
+	q.changed(t)
+	if q.OnUpdate != nil {
+		q.OnUpdate(t)
+	}
+}
+
+func (q *TransferQueue) changed(t *Transfer) {
+	if q.checkpoint != nil {
+		q.checkpoint(t)
+	}
+}
+
+func (q *TransferQueue) worker() {
+	defer q.wg.Done()
+	for {
+		q.mu.Lock()
+		for !q.closed && (q.paused || len(q.pending) == 0) {
+			q.cond.Wait()
+		}
+		if q.closed {
+			q.mu.Unlock()
+			return
+		}
+		q.mu.Unlock()
+
+		if q.control != nil {
+			q.control.acquire()
+		}
+		q.mu.Lock()
+		if q.closed || q.paused || len(q.pending) == 0 {
+			// Another worker took the transfer while this one waited.
+			q.mu.Unlock()
+			if q.control != nil {
+				q.control.release()
+			}
+			continue
+		}
+		t := heap.Pop(&q.pending).(*Transfer)
+		q.mu.Unlock()
+
+		q.update(t, TransferRunning, nil, nil)
+		meta, err := q.run(t)
+		if q.control != nil {
+			q.control.release()
+		}
+		if err != nil {
+			q.update(t, TransferFailed, nil, err)
+		} else {
+			q.update(t, TransferDone, meta, nil)
+		}
+	}
+}
+
+// Pause stops the queue from starting transfers until Resume is called.
+func (q *TransferQueue) Pause() {
+	q.mu.Lock()
+	q.paused = true
+	q.mu.Unlock()
+}
+
+// Resume restarts a paused queue.
+func (q *TransferQueue) Resume() {
+	q.mu.Lock()
+	q.paused = false
+	q.cond.Broadcast()
+	q.mu.Unlock()
+}
+
+// Paused reports whether the queue is paused.
+func (q *TransferQueue) Paused() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.paused
+}
+
+// Transfers returns all the transfers added to the queue, in the order they
+// were added.
+func (q *TransferQueue) Transfers() []*Transfer {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]*Transfer{}, q.all...)
+}
+
+// Wait waits for all the transfers added to the queue so far to finish, and
+// returns the error of the first one which failed, if any.
+func (q *TransferQueue) Wait() error {
+	var first error
+	for _, t := range q.Transfers() {
+		if _, err := t.Wait(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// Close cancels the transfers which haven't started with ErrQueueClosed, and
+// waits for the running ones to finish.
+func (q *TransferQueue) Close() {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		q.wg.Wait()
+		return
+	}
+	q.closed = true
+	pending := q.pending
+	q.pending = nil
+	q.cond.Broadcast()
+	q.mu.Unlock()
+
+	for _, t := range pending {
+		q.update(t, TransferCanceled, nil, ErrQueueClosed)
+	}
+	q.wg.Wait()
+}
+
+// An UploadQueue uploads local files with a TransferQueue.
+type UploadQueue struct {
+	*TransferQueue
+	client *Client
+}
+
+// NewUploadQueue creates an UploadQueue which performs requests using c, with
+// the given number of workers.
+func NewUploadQueue(c *Client, workers int) *UploadQueue {
+	return newUploadQueue(c, workers, nil)
+}
+
+func newUploadQueue(c *Client, workers int, control *ConcurrencyControl) *UploadQueue {
+	uq := &UploadQueue{client: c}
+	uq.TransferQueue = newTransferQueue(workers, control, uq.upload)
+	return uq
+}
+
+// Add queues an upload of the file at localPath to remotePath, with the given
+// priority and upload options, which may be nil.
+func (uq *UploadQueue) Add(localPath, remotePath string, priority int, opts *UploadOptions) *Transfer {
+	t := newTransfer()
+	t.Upload = true
+	t.Local = localPath
+	t.Remote = remotePath
+	t.Options = opts
+	t.Priority = priority
+	if info, err := os.Stat(localPath); err == nil {
+		t.size = info.Size()
+	}
+	return uq.add(t)
+}
+
+func (uq *UploadQueue) upload(t *Transfer) (*Metadata, error) {
+	f, err := os.Open(t.Local)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, errors.New(t.Local + " is a directory")
+	}
+
+	opts := t.Options.withMTime(info.ModTime())
+	atomic.StoreInt64(&t.size, info.Size())
+	atomic.StoreInt64(&t.transferred, 0)
+	if info.Size() <= DefaultChunkSize {
+		return uq.client.PutFileWithOptions(t.Remote, progressReader{f, t}, info.Size(), opts)
+	}
+
+	done := uq.client.trackUpload(t.Remote)
+	meta, err := uq.uploadChunks(t, f, info.Size(), opts)
+	if t.resumed && errors.Is(err, ErrNotFound) {
+		// The session of a resumed upload may have expired or been
+		// abandoned by the server, which then doesn't know its id, so
+		// start again from scratch.
+		t.resumed = false
+		t.setSession(nil)
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			done(nil, 0, err)
+			return nil, err
+		}
+		meta, err = uq.uploadChunks(t, f, info.Size(), opts)
+	}
+	done(meta, info.Size(), err)
+	return meta, err
+}
+
+// uploadChunks uploads f with a chunked upload, continuing the session of t if
+// it has one. The session is recorded in t after every chunk.
+func (uq *UploadQueue) uploadChunks(t *Transfer, f *os.File, size int64, opts *UploadOptions) (*Metadata, error) {
+	state := &ChunkedUpload{}
+	if session := t.session(); session != nil {
+		if _, err := f.Seek(session.Offset, io.SeekStart); err != nil {
+			return nil, err
+		}
+		state = session
+		t.resumed = true
+	}
+	atomic.StoreInt64(&t.transferred, state.Offset)
+
+	for state.UploadId == "" || state.Offset < size {
+		chunkSize := uq.client.nextChunkSize(0)
+		buf := getChunkBuffer(chunkSize)
+		chunk := (*buf)[:chunkSize]
+		n, err := io.ReadFull(f, chunk)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			putChunkBuffer(buf)
+			return nil, err
+		}
+		next, err := uq.client.sendChunk(state, chunk[:n], true)
+		putChunkBuffer(buf)
+		if err != nil {
+			return nil, err
+		}
+		state = next
+		t.count(n)
+		t.setSession(state)
+		uq.changed(t)
+		if n == 0 {
+			break
+		}
+	}
+
+	meta, err := uq.client.CommitChunkedUploadWithOptions(t.Remote, state.UploadId, opts)
+	if err == nil {
+		t.setSession(nil)
+	}
+	return meta, err
+}
+
+// A DownloadQueue downloads files to local paths with a TransferQueue. Failed
+// downloads are retried up to MaxRetries times, unless the file doesn't exist
+// or the client isn't authorized, and requests to download a file which is
+// already queued or running share the existing transfer.
+type DownloadQueue struct {
+	*TransferQueue
+	MaxRetries int
+	RetryDelay time.Duration // the delay before the first retry, doubled for each further one
+
+	client *Client
+	mu     sync.Mutex
+	active map[string]*Transfer
+}
+
+// NewDownloadQueue creates a DownloadQueue which performs requests using c,
+// with the given number of workers.
+func NewDownloadQueue(c *Client, workers int) *DownloadQueue {
+	return newDownloadQueue(c, workers, nil)
+}
+
+func newDownloadQueue(c *Client, workers int, control *ConcurrencyControl) *DownloadQueue {
+	dq := &DownloadQueue{
+		MaxRetries: 3,
+		RetryDelay: time.Second,
+		client:     c,
+		active:     make(map[string]*Transfer),
+	}
+	dq.TransferQueue = newTransferQueue(workers, control, dq.download)
+	return dq
+}
+
+// Add queues a download of the file at remotePath (at revision rev if it is
+// not the empty string) to localPath, with the given priority. If the same
+// download is already queued or running, its Transfer is returned instead.
+func (dq *DownloadQueue) Add(remotePath, rev, localPath string, priority int) *Transfer {
+	key := NormalizePath(remotePath) + "\x00" + rev + "\x00" + filepath.Clean(localPath)
+
+	dq.mu.Lock()
+	defer dq.mu.Unlock()
+	if t, ok := dq.active[key]; ok && t.State() < TransferDone {
+		return t
+	}
+	t := newTransfer()
+	t.Local = localPath
+	t.Remote = remotePath
+	t.Rev = rev
+	t.Priority = priority
+	dq.active[key] = t
+	go func() {
+		<-t.Done()
+		dq.mu.Lock()
+		if dq.active[key] == t {
+			delete(dq.active, key)
+		}
+		dq.mu.Unlock()
+	}()
+	return dq.add(t)
+}
+
+func (dq *DownloadQueue) download(t *Transfer) (*Metadata, error) {
+	delay := dq.RetryDelay
+	for attempt := 0; ; attempt++ {
+		meta, err := dq.client.downloadToPath(t.Remote, t.Rev, t.Local, t)
+		if err == nil || attempt >= dq.MaxRetries ||
+			errors.Is(err, ErrNotFound) || errors.Is(err, ErrUnauthorized) {
+			return meta, err
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
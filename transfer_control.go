@@ -0,0 +1,130 @@
+package dropbox
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Defaults of a ConcurrencyControl.
+const (
+	DefaultMinConcurrency = 1
+	DefaultMaxConcurrency = 16
+	DefaultLatencyTarget  = 10 * time.Second
+)
+
+// A ConcurrencyControl adjusts how many transfers run at once with an AIMD
+// (additive increase, multiplicative decrease) scheme, as TCP does with its
+// congestion window. While requests succeed within LatencyTarget, the limit
+// grows by about one transfer for every limit requests. When Dropbox answers
+// with 429 or a 5xx status, or a request fails, the limit is halved, at most
+// once per LatencyTarget so a burst of failures counts as one. Slow requests
+// stop the limit from growing.
+type ConcurrencyControl struct {
+	Min, Max      int           // Bounds of the limit, DefaultMinConcurrency and DefaultMaxConcurrency if zero
+	LatencyTarget time.Duration // Slowest healthy request, DefaultLatencyTarget if zero
+
+	mu       sync.Mutex
+	cond     *sync.Cond
+	limit    float64
+	active   int
+	decrease time.Time // time of the last decrease
+}
+
+func (cc *ConcurrencyControl) bounds() (min, max int) {
+	min, max = cc.Min, cc.Max
+	if min <= 0 {
+		min = DefaultMinConcurrency
+	}
+	if max <= 0 {
+		max = DefaultMaxConcurrency
+	}
+	if max < min {
+		max = min
+	}
+	return min, max
+}
+
+func (cc *ConcurrencyControl) target() time.Duration {
+	if cc.LatencyTarget > 0 {
+		return cc.LatencyTarget
+	}
+	return DefaultLatencyTarget
+}
+
+// init sets up cc, with cc.mu held.
+func (cc *ConcurrencyControl) init() {
+	if cc.cond == nil {
+		cc.cond = sync.NewCond(&cc.mu)
+		min, _ := cc.bounds()
+		cc.limit = float64(min)
+	}
+}
+
+// Limit returns the number of transfers currently allowed to run at once.
+func (cc *ConcurrencyControl) Limit() int {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.init()
+	return int(cc.limit)
+}
+
+// acquire waits until another transfer may run.
+func (cc *ConcurrencyControl) acquire() {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.init()
+	for cc.active >= int(cc.limit) {
+		cc.cond.Wait()
+	}
+	cc.active++
+}
+
+// release ends a transfer started by acquire.
+func (cc *ConcurrencyControl) release() {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.active--
+	cc.cond.Broadcast()
+}
+
+// observe adjusts the limit after a request.
+func (cc *ConcurrencyControl) observe(resp *http.Response, err error, d time.Duration) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.init()
+	min, max := cc.bounds()
+
+	if err != nil || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		if time.Since(cc.decrease) < cc.target() {
+			return
+		}
+		cc.decrease = time.Now()
+		if cc.limit /= 2; cc.limit < float64(min) {
+			cc.limit = float64(min)
+		}
+		return
+	}
+	if d > cc.target() {
+		return
+	}
+	if cc.limit += 1 / cc.limit; cc.limit > float64(max) {
+		cc.limit = float64(max)
+	}
+	cc.cond.Broadcast()
+}
+
+// NewAdaptiveTransferManager creates a TransferManager which performs requests
+// using c, and lets cc decide how many transfers run at once, rather than a
+// fixed number of workers. The limit is shared by uploads and downloads. A cc
+// must not be used by more than one TransferManager.
+func NewAdaptiveTransferManager(c *Client, cc *ConcurrencyControl) *TransferManager {
+	clone := *c
+	clone.observe = cc.observe
+	_, max := cc.bounds()
+	return &TransferManager{
+		Uploads:   newUploadQueue(&clone, max, cc),
+		Downloads: newDownloadQueue(&clone, max, cc),
+		start:     time.Now(),
+	}
+}
@@ -0,0 +1,279 @@
+package dropbox
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// RateWindow is the period over which TransferStats.Rate is measured.
+const RateWindow = 5 * time.Second
+
+// ManifestInterval is how long a TransferManager waits after a change before
+// writing its manifest, so bursts of changes are written at once.
+const ManifestInterval = time.Second
+
+// TransferStats summarizes the transfers of a TransferManager. Sizes include
+// only the transfers which haven't failed or been canceled, and the size of a
+// download is only known once it has started.
+type TransferStats struct {
+	Queued, Running, Done, Failed, Canceled int
+
+	Total       int64 // bytes to transfer
+	Transferred int64 // bytes transferred so far
+	Remaining   int64 // Total - Transferred
+
+	Rate        float64       // bytes per second over the last RateWindow
+	AverageRate float64       // bytes per second since the manager started
+	ETA         time.Duration // estimated time to transfer Remaining, zero if unknown
+}
+
+// A TransferManager owns an upload and a download queue, and reports the
+// progress of all their transfers, eg: for display in a user interface.
+type TransferManager struct {
+	Uploads   *UploadQueue
+	Downloads *DownloadQueue
+
+	start   time.Time
+	mu      sync.Mutex
+	samples []rateSample
+
+	manifest    string
+	saveMu      sync.Mutex
+	savePending bool  // a write of the manifest is scheduled
+	saveErr     error // error of the last write of the manifest
+}
+
+type rateSample struct {
+	at    time.Time
+	bytes int64
+}
+
+// NewTransferManager creates a TransferManager which performs requests using
+// c, with the given number of workers for each queue.
+func NewTransferManager(c *Client, workers int) *TransferManager {
+	return &TransferManager{
+		Uploads:   NewUploadQueue(c, workers),
+		Downloads: NewDownloadQueue(c, workers),
+		start:     time.Now(),
+	}
+}
+
+// A transferRecord is an unfinished transfer in a manifest.
+type transferRecord struct {
+	Upload   bool           `json:"upload"`
+	Local    string         `json:"local"`
+	Remote   string         `json:"remote"`
+	Rev      string         `json:"rev,omitempty"`
+	Options  *UploadOptions `json:"options,omitempty"`
+	Priority int            `json:"priority"`
+	Session  *ChunkedUpload `json:"session,omitempty"`
+}
+
+// OpenTransferManager creates a TransferManager like NewTransferManager, which
+// saves its unfinished transfers to the manifest file when they change,
+// including after every chunk of a chunked upload, at most once every
+// ManifestInterval. Wait and Close write it at once. The transfers found in
+// an existing manifest are queued again, and chunked uploads continue from
+// their last completed chunk, so an agent that crashed or was restarted
+// resumes where it left off. A manifest which can't be written is retried at
+// the next change, and the error is reported by Err.
+func OpenTransferManager(c *Client, workers int, manifest string) (*TransferManager, error) {
+	var records []transferRecord
+	data, err := ioutil.ReadFile(manifest)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &records); err != nil {
+			return nil, err
+		}
+	}
+
+	tm := NewTransferManager(c, workers)
+	tm.manifest = manifest
+	tm.Pause()
+	tm.Uploads.checkpoint = tm.checkpoint
+	tm.Downloads.checkpoint = tm.checkpoint
+	for _, r := range records {
+		if r.Upload {
+			t := newTransfer()
+			t.Upload = true
+			t.Local = r.Local
+			t.Remote = r.Remote
+			t.Options = r.Options
+			t.Priority = r.Priority
+			t.current = r.Session
+			if info, err := os.Stat(r.Local); err == nil {
+				t.size = info.Size()
+			}
+			tm.Uploads.add(t)
+		} else {
+			tm.Downloads.Add(r.Remote, r.Rev, r.Local, r.Priority)
+		}
+	}
+	tm.Resume()
+	return tm, nil
+}
+
+// checkpoint schedules a write of the manifest, unless one is pending.
+func (tm *TransferManager) checkpoint(*Transfer) {
+	tm.saveMu.Lock()
+	defer tm.saveMu.Unlock()
+	if tm.savePending {
+		return
+	}
+	tm.savePending = true
+	time.AfterFunc(ManifestInterval, func() { tm.Flush() })
+}
+
+// Flush writes the manifest of a TransferManager created by
+// OpenTransferManager at once, and returns the error, if any.
+func (tm *TransferManager) Flush() error {
+	if tm.manifest == "" {
+		return nil
+	}
+	tm.saveMu.Lock()
+	defer tm.saveMu.Unlock()
+	tm.savePending = false
+	tm.saveErr = tm.save()
+	return tm.saveErr
+}
+
+// Err returns the error of the last write of the manifest, which is nil once
+// a write succeeds again.
+func (tm *TransferManager) Err() error {
+	tm.saveMu.Lock()
+	defer tm.saveMu.Unlock()
+	return tm.saveErr
+}
+
+// save writes the unfinished transfers to the manifest.
+func (tm *TransferManager) save() error {
+	records := make([]transferRecord, 0)
+	for _, t := range tm.Transfers() {
+		// Transfers canceled by Close are kept, to resume next time.
+		if state := t.State(); state == TransferDone || state == TransferFailed {
+			continue
+		}
+		records = append(records, transferRecord{
+			Upload:   t.Upload,
+			Local:    t.Local,
+			Remote:   t.Remote,
+			Rev:      t.Rev,
+			Options:  t.Options,
+			Priority: t.Priority,
+			Session:  t.session(),
+		})
+	}
+	data, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(tm.manifest, data, 0600)
+}
+
+// Transfers returns the transfers of both queues, uploads first.
+func (tm *TransferManager) Transfers() []*Transfer {
+	return append(tm.Uploads.Transfers(), tm.Downloads.Transfers()...)
+}
+
+// Pause pauses both queues.
+func (tm *TransferManager) Pause() {
+	tm.Uploads.Pause()
+	tm.Downloads.Pause()
+}
+
+// Resume resumes both queues.
+func (tm *TransferManager) Resume() {
+	tm.Uploads.Resume()
+	tm.Downloads.Resume()
+}
+
+// Wait waits for all transfers added so far to finish, and returns the error
+// of the first one which failed, if any, or else the error writing the
+// manifest.
+func (tm *TransferManager) Wait() error {
+	err := tm.Uploads.Wait()
+	if derr := tm.Downloads.Wait(); err == nil {
+		err = derr
+	}
+	if ferr := tm.Flush(); err == nil {
+		err = ferr
+	}
+	return err
+}
+
+// Close closes both queues, and writes the manifest.
+func (tm *TransferManager) Close() {
+	tm.Uploads.Close()
+	tm.Downloads.Close()
+	tm.Flush()
+}
+
+// Stats returns the current progress of the transfers.
+func (tm *TransferManager) Stats() TransferStats {
+	var stats TransferStats
+	for _, t := range tm.Transfers() {
+		state := t.State()
+		switch state {
+		case TransferQueued:
+			stats.Queued++
+		case TransferRunning:
+			stats.Running++
+		case TransferDone:
+			stats.Done++
+		case TransferFailed:
+			stats.Failed++
+		case TransferCanceled:
+			stats.Canceled++
+		}
+		if state == TransferFailed || state == TransferCanceled {
+			continue
+		}
+		transferred, size := t.Progress()
+		stats.Total += size
+		stats.Transferred += transferred
+	}
+	stats.Remaining = stats.Total - stats.Transferred
+	if stats.Remaining < 0 {
+		stats.Remaining = 0
+	}
+
+	now := time.Now()
+	if elapsed := now.Sub(tm.start).Seconds(); elapsed > 0 {
+		stats.AverageRate = float64(stats.Transferred) / elapsed
+	}
+	stats.Rate = tm.sample(now, stats.Transferred)
+	if rate := stats.Rate; rate > 0 && stats.Remaining > 0 {
+		stats.ETA = time.Duration(float64(stats.Remaining) / rate * float64(time.Second))
+	}
+	return stats
+}
+
+// sample records the number of bytes transferred at now, and returns the
+// rate since the oldest sample within RateWindow.
+func (tm *TransferManager) sample(now time.Time, bytes int64) float64 {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	if len(tm.samples) == 0 {
+		tm.samples = append(tm.samples, rateSample{tm.start, 0})
+	}
+	tm.samples = append(tm.samples, rateSample{now, bytes})
+	// Keep one sample older than the window as the reference.
+	i := 0
+	for i < len(tm.samples)-2 && now.Sub(tm.samples[i+1].at) >= RateWindow {
+		i++
+	}
+	tm.samples = tm.samples[i:]
+
+	first := tm.samples[0]
+	elapsed := now.Sub(first.at).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(bytes-first.bytes) / elapsed
+}
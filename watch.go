@@ -0,0 +1,251 @@
+package dropbox
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// An Op is the kind of a change reported by a Watcher.
+type Op int
+
+// Kinds of change reported by a Watcher.
+const (
+	Create Op = iota // A file or folder was created
+	Write            // A file's content changed
+	Remove           // A file or folder was removed
+	Rename           // A file was moved from OldPath
+)
+
+var opNames = []string{"CREATE", "WRITE", "REMOVE", "RENAME"}
+
+func (op Op) String() string {
+	if op >= 0 && int(op) < len(opNames) {
+		return opNames[op]
+	}
+	return fmt.Sprintf("Op(%d)", int(op))
+}
+
+// An Event is a change to a file or folder seen by a Watcher.
+type Event struct {
+	Op      Op
+	Path    string    // Lower-cased path of the entry
+	OldPath string    // Lower-cased previous path, for Rename
+	Meta    *Metadata // New metadata, nil for Remove
+}
+
+func (e Event) String() string {
+	if e.Op == Rename {
+		return fmt.Sprintf("%s %s -> %s", e.Op, e.OldPath, e.Path)
+	}
+	return fmt.Sprintf("%s %s", e.Op, e.Path)
+}
+
+// Timing of a Watcher.
+const (
+	WatchTimeout    = 120             // seconds each longpoll waits for changes
+	watchRetryDelay = 5 * time.Second // first delay after an error
+	watchMaxDelay   = 2 * time.Minute // longest delay after repeated errors
+)
+
+// A Watcher reports changes below a folder of a dropbox as they happen, in the
+// style of fsnotify. Changes are delivered on Events, and errors, after which
+// the watcher keeps trying, on Errors. Both channels must be read.
+type Watcher struct {
+	Events <-chan Event
+	Errors <-chan error
+
+	client *Client
+	path   string
+	events chan Event
+	errors chan error
+	stop   chan struct{}
+	once   sync.Once
+
+	mu       sync.Mutex
+	handlers []changeHandler
+}
+
+type changeHandler struct {
+	prefix string
+	fn     func(Event)
+}
+
+// Watch starts watching the folder at path and everything inside it. The
+// current contents are listed first, without producing events, so later
+// changes can be told apart from creations. If the delta sequence is reset by
+// the server, every entry is reported again as a Create.
+func (c *Client) Watch(path string) *Watcher {
+	w := &Watcher{
+		client: c,
+		path:   NormalizePath(path),
+		events: make(chan Event),
+		errors: make(chan error),
+		stop:   make(chan struct{}),
+	}
+	w.Events = w.events
+	w.Errors = w.errors
+	go w.run()
+	return w
+}
+
+// OnChange registers fn to be called with every event affecting the path
+// prefix or anything inside it, eg: to invalidate cached thumbnails or
+// previews of the files. An event affects the prefix if its Path or OldPath
+// is inside it, or if it removes or renames a folder containing it. The
+// callbacks are run by the watcher before the event is delivered on Events,
+// so they must not block for long, and Events must still be read.
+func (w *Watcher) OnChange(prefix string, fn func(Event)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.handlers = append(w.handlers, changeHandler{NormalizePath(prefix), fn})
+}
+
+// dispatch calls the callbacks registered for the paths affected by ev.
+func (w *Watcher) dispatch(ev Event) {
+	w.mu.Lock()
+	handlers := w.handlers
+	w.mu.Unlock()
+	for _, h := range handlers {
+		if affects(ev, h.prefix) {
+			h.fn(ev)
+		}
+	}
+}
+
+// affects reports whether ev changes prefix or anything inside it.
+func affects(ev Event, prefix string) bool {
+	if HasPrefixPath(ev.Path, prefix) {
+		return true
+	}
+	if ev.Op == Rename && HasPrefixPath(ev.OldPath, prefix) {
+		return true
+	}
+	switch ev.Op {
+	case Remove:
+		return HasPrefixPath(prefix, ev.Path)
+	case Rename:
+		return HasPrefixPath(prefix, ev.OldPath)
+	}
+	return false
+}
+
+// Close stops the watcher. Events and Errors are closed once a pending
+// longpoll returns.
+func (w *Watcher) Close() {
+	w.once.Do(func() { close(w.stop) })
+}
+
+func (w *Watcher) run() {
+	defer close(w.events)
+	defer close(w.errors)
+
+	var classifier ChangeClassifier
+	cursor := ""
+	seeded := false
+	delay := watchRetryDelay
+	for {
+		select {
+		case <-w.stop:
+			return
+		default:
+		}
+		if seeded {
+			changes, backoff, err := w.client.LongpollDelta(cursor, WatchTimeout)
+			if err != nil {
+				if !w.fail(err, &delay) {
+					return
+				}
+				continue
+			}
+			if backoff > 0 && !w.sleep(time.Duration(backoff)*time.Second) {
+				return
+			}
+			if !changes {
+				continue
+			}
+		}
+
+		for {
+			delta, err := w.client.DeltaPrefix(cursor, w.path)
+			if err != nil {
+				if !w.fail(err, &delay) {
+					return
+				}
+				break
+			}
+			events := classifier.Classify(delta)
+			cursor = delta.Cursor
+			if seeded && !w.send(events) {
+				return
+			}
+			if !delta.HasMore {
+				seeded = true
+				delay = watchRetryDelay
+				break
+			}
+		}
+	}
+}
+
+// send delivers the events of a page of changes.
+func (w *Watcher) send(changes []ChangeEvent) bool {
+	renamed := make(map[string]bool)
+	for _, c := range changes {
+		if c.OldPath != "" {
+			renamed[c.OldPath] = true
+		}
+	}
+	for _, c := range changes {
+		if !HasPrefixPath(c.Path, w.path) {
+			continue
+		}
+		ev := Event{Path: c.Path, Meta: c.Meta}
+		switch {
+		case c.Kind == Deleted && renamed[c.Path]:
+			continue
+		case c.Kind == Deleted:
+			ev.Op = Remove
+		case c.OldPath != "":
+			ev.Op = Rename
+			ev.OldPath = c.OldPath
+		case c.Kind == Modified:
+			ev.Op = Write
+		default:
+			ev.Op = Create
+		}
+		w.dispatch(ev)
+		select {
+		case w.events <- ev:
+		case <-w.stop:
+			return false
+		}
+	}
+	return true
+}
+
+// fail reports err and waits before the next attempt, doubling the delay.
+func (w *Watcher) fail(err error, delay *time.Duration) bool {
+	select {
+	case w.errors <- err:
+	case <-w.stop:
+		return false
+	}
+	ok := w.sleep(*delay)
+	if *delay *= 2; *delay > watchMaxDelay {
+		*delay = watchMaxDelay
+	}
+	return ok
+}
+
+// sleep waits for d, and reports false if the watcher was closed meanwhile.
+func (w *Watcher) sleep(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-w.stop:
+		return false
+	}
+}
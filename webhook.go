@@ -0,0 +1,209 @@
+package dropbox
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Parameters of webhook notifications.
+const (
+	SignatureHeader = "X-Dropbox-Signature" // HMAC-SHA256 of a notification, keyed with the app secret
+	MaxWebhookBody  = 1 << 20               // Largest notification accepted
+)
+
+// DefaultDebounce is how long a WebhookDispatcher waits for further
+// notifications before processing a user's changes.
+const DefaultDebounce = time.Second
+
+// A WebhookHandler serves the webhook URI of an app. It answers the challenge
+// Dropbox sends when the URI is registered, and passes the UIDs of the users
+// whose dropbox changed to Notify. Notifications which aren't signed with
+// AppSecret are rejected. Dropbox expects a quick answer, so Notify should
+// only schedule the processing of the changes, as a WebhookDispatcher does.
+type WebhookHandler struct {
+	AppSecret string
+	Notify    func(uids []uint64)
+}
+
+func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Write([]byte(r.FormValue("challenge")))
+	case "POST":
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, MaxWebhookBody))
+		if err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		mac := hmac.New(sha256.New, []byte(h.AppSecret))
+		mac.Write(body)
+		sig, err := hex.DecodeString(r.Header.Get(SignatureHeader))
+		if err != nil || !hmac.Equal(sig, mac.Sum(nil)) {
+			http.Error(w, "invalid signature", http.StatusForbidden)
+			return
+		}
+		var n struct {
+			Delta struct {
+				Users []uint64 `json:"users"`
+			} `json:"delta"`
+		}
+		if err := json.Unmarshal(body, &n); err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		if h.Notify != nil && len(n.Delta.Users) > 0 {
+			h.Notify(n.Delta.Users)
+		}
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// A DeltaProcessor handles a page of changes to a user's dropbox. If it
+// returns an error, the page is offered again at the next notification.
+type DeltaProcessor func(uid uint64, delta *Delta) error
+
+// A WebhookDispatcher processes the changes of the users named in webhook
+// notifications. Each registered user has a Client, a DeltaProcessor and a
+// delta cursor, which is advanced as pages are processed. Notifications are
+// debounced: a user's changes are fetched Debounce after the first
+// notification of a burst, and notifications arriving while they are being
+// processed cause one more pass afterwards, so each change is processed once
+// and a user is never processed concurrently.
+//
+// Use Notify as the Notify function of a WebhookHandler.
+type WebhookDispatcher struct {
+	Debounce time.Duration               // DefaultDebounce if zero
+	OnError  func(uid uint64, err error) // called when fetching or processing changes fails, if set
+
+	mu    sync.Mutex
+	users map[uint64]*webhookUser
+}
+
+type webhookUser struct {
+	client  *Client
+	process DeltaProcessor
+	cursor  string
+
+	scheduled bool // a pass is pending or running
+	again     bool // another pass is needed once the running one completes
+	running   bool
+}
+
+// NewWebhookDispatcher creates an empty WebhookDispatcher.
+func NewWebhookDispatcher() *WebhookDispatcher {
+	return &WebhookDispatcher{users: make(map[uint64]*webhookUser)}
+}
+
+// Register makes d process the changes of the user with the given UID with
+// process, starting from cursor, using c, which must be authorized as the
+// user. An empty cursor starts with the whole content of the dropbox. An
+// existing registration of the user is replaced.
+func (d *WebhookDispatcher) Register(uid uint64, c *Client, cursor string, process DeltaProcessor) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.users[uid] = &webhookUser{client: c, process: process, cursor: cursor}
+}
+
+// Unregister stops d from processing the changes of the user with the given
+// UID. A pass which is running completes.
+func (d *WebhookDispatcher) Unregister(uid uint64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.users, uid)
+}
+
+// Cursor returns the delta cursor of the user with the given UID, which should
+// be saved to Register the user again after a restart.
+func (d *WebhookDispatcher) Cursor(uid uint64) (string, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	u, ok := d.users[uid]
+	if !ok {
+		return "", false
+	}
+	return u.cursor, true
+}
+
+// Notify schedules the processing of the changes of the given users.
+// Unregistered users are ignored.
+func (d *WebhookDispatcher) Notify(uids []uint64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, uid := range uids {
+		u, ok := d.users[uid]
+		if !ok {
+			continue
+		}
+		if u.running {
+			u.again = true
+			continue
+		}
+		if u.scheduled {
+			continue
+		}
+		u.scheduled = true
+		uid := uid
+		time.AfterFunc(d.debounce(), func() { d.run(uid, u) })
+	}
+}
+
+func (d *WebhookDispatcher) debounce() time.Duration {
+	if d.Debounce > 0 {
+		return d.Debounce
+	}
+	return DefaultDebounce
+}
+
+// run processes the changes of u until no notification arrived meanwhile.
+func (d *WebhookDispatcher) run(uid uint64, u *webhookUser) {
+	d.mu.Lock()
+	u.running = true
+	for {
+		u.again = false
+		cursor := u.cursor
+		d.mu.Unlock()
+
+		cursor, err := d.process(uid, u, cursor)
+
+		d.mu.Lock()
+		u.cursor = cursor
+		if err != nil && d.OnError != nil {
+			d.mu.Unlock()
+			d.OnError(uid, err)
+			d.mu.Lock()
+		}
+		if !u.again {
+			break
+		}
+	}
+	u.running = false
+	u.scheduled = false
+	d.mu.Unlock()
+}
+
+// process passes all the pages of changes after cursor to the processor of u,
+// returning the cursor of the last page processed.
+func (d *WebhookDispatcher) process(uid uint64, u *webhookUser, cursor string) (string, error) {
+	for {
+		delta, err := u.client.Delta(cursor)
+		if err != nil {
+			return cursor, err
+		}
+		if err := u.process(uid, delta); err != nil {
+			return cursor, err
+		}
+		cursor = delta.Cursor
+		if !delta.HasMore {
+			return cursor, nil
+		}
+	}
+}